@@ -10,10 +10,12 @@ import (
 // Name: The long form Name of the Option, e.g. --help
 // Contraction: The short Name of the Option, e.g. -h
 // NeedsValue: Whether this Option needs and allows a value associated with it
+// Repeatable: Whether this Option may be given more than once, collecting all its values
 type Option struct {
 	Name        string
 	Contraction string
 	NeedsValue  bool
+	Repeatable  bool
 }
 
 type Command struct {
@@ -29,6 +31,7 @@ type Command struct {
 //
 // All positional arguments must come before all Option arguments.
 // Options may have a value associated with them, in the form "--key=value" or "--key value".
+// A Repeatable Option may be given more than once, in which case its values are joined with newlines.
 // Each Option has a long version, prefixed with --, and a short version, prefixed with -.
 // Using the wrong prefix will result in the Option not being recognised.
 // The --help and -h flags are excluded from the options; instead hashHelpFlag is set.
@@ -41,6 +44,7 @@ type Command struct {
 // - An unknown Option is given
 // - An Option that requires a value isn't given one
 // - An Option that doesn't require a value is given one
+// - An Option that isn't Repeatable is given more than once
 // - A positional argument is found after an Option (interpreted as a value with no corresponding Option flag)
 func ParseArgs(args []string, allOptions []Option) ([]string, map[string]string, bool, error) {
 	var positionals []string
@@ -92,12 +96,15 @@ func ParseArgs(args []string, allOptions []Option) ([]string, map[string]string,
 			}
 
 			if opt.Name == "" {
-				return nil, nil, false, optionError("Bad Option", Option{key, key, false}, usedContraction)
+				return nil, nil, false, optionError("Bad Option", Option{key, key, false, false}, usedContraction)
 			}
 
 			if value != "" {
 				if opt.NeedsValue {
-					options[opt.Name] = value
+					err := addOptionValue(options, opt, value, usedContraction)
+					if err != nil {
+						return nil, nil, false, err
+					}
 				} else {
 					return nil, nil, false, optionError("Option doesn't take a value", opt, usedContraction)
 				}
@@ -110,12 +117,18 @@ func ParseArgs(args []string, allOptions []Option) ([]string, map[string]string,
 					hasHelpFlag = true
 				} else {
 					// Option is present but has no value.
-					options[opt.Name] = ""
+					err := addOptionValue(options, opt, "", usedContraction)
+					if err != nil {
+						return nil, nil, false, err
+					}
 				}
 			}
 		} else if openOption.Name != "" {
 			//  If the last Option had no value provided with = but needed one, this argument becomes its value.
-			options[openOption.Name] = arg
+			err := addOptionValue(options, openOption, arg, usedContraction)
+			if err != nil {
+				return nil, nil, false, err
+			}
 			// Reset to an empty Option.
 			openOption = Option{}
 		} else {
@@ -136,6 +149,30 @@ func ParseArgs(args []string, allOptions []Option) ([]string, map[string]string,
 	return positionals, options, hasHelpFlag, nil
 }
 
+// Set the value of an Option, adding it to any values already given if the Option is Repeatable.
+func addOptionValue(options map[string]string, opt Option, value string, usedContraction bool) error {
+	existing, ok := options[opt.Name]
+	if !ok {
+		options[opt.Name] = value
+		return nil
+	}
+	if !opt.Repeatable {
+		return optionError("Option given more than once", opt, usedContraction)
+	}
+	options[opt.Name] = existing + "\n" + value
+	return nil
+}
+
+// Get all the values given for an Option that may be repeated.
+// Returns nil if the Option wasn't given.
+func optionValues(options map[string]string, name string) []string {
+	value, ok := options[name]
+	if !ok {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
 // Split the given string around the fist occurrence of the given substring.
 // If the substring is not found, the input string is returned as the first string and "" as the second.
 func splitAtFirst(str string, sub string) (string, string) {
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testOptions = []Option{
+	{"help", "h", false, false},
+	{"force", "f", false, false},
+	{"to", "T", true, false},
+	{"trailer", "t", true, true},
+}
+
+func TestParseArgs(t *testing.T) {
+	args := []string{"metro", "move", "2", "--to", "feature", "-f", "--trailer=A=1", "-t", "B=2"}
+	positionals, options, help, err := ParseArgs(args, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(positionals, []string{"move", "2"}) {
+		t.Errorf("positionals = %v", positionals)
+	}
+	if help {
+		t.Error("help flag set without --help")
+	}
+	want := map[string]string{"to": "feature", "force": "", "trailer": "A=1\nB=2"}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %v, want %v", options, want)
+	}
+	if values := optionValues(options, "trailer"); !reflect.DeepEqual(values, []string{"A=1", "B=2"}) {
+		t.Errorf("optionValues = %v", values)
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"metro", "move", "--to", "a", "--to", "b"},
+		{"metro", "move", "-T", "a", "--to=b"},
+		{"metro", "delete", "--force", "-f"},
+		{"metro", "move", "--to"},
+		{"metro", "move", "--force=yes"},
+		{"metro", "move", "--unknown"},
+		{"metro", "move", "--force", "stray"},
+	} {
+		if _, _, _, err := ParseArgs(args, testOptions); err == nil {
+			t.Errorf("ParseArgs(%v) succeeded, want error", args)
+		}
+	}
+}
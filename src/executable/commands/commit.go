@@ -7,31 +7,89 @@ import (
 	"metro"
 )
 
-func execCommit(repo *git.Repository, positionals []string, _ map[string]string) error {
-	if len(positionals) < 1 {
-		return errors.New("Message required.")
-	}
+func execCommit(repo *git.Repository, positionals []string, options map[string]string) error {
 	if len(positionals) > 1 {
 		return errors.New("Unexpected argument: " + positionals[1])
 	}
-	message := positionals[0]
 
 	err := metro.AssertMerging(repo)
 	if err != nil {
 		return err
 	}
 
-	err = metro.Commit(repo, message, "HEAD^{commit}")
+	var message string
+	if len(positionals) == 1 {
+		message = positionals[0]
+	} else {
+		// Without a message, let the user write one starting from the template.
+		template, err := metro.MessageTemplate(repo)
+		if err != nil {
+			return err
+		}
+		if template == "" {
+			return errors.New("Message required.")
+		}
+		message, err = editMessage(template, repo)
+		if err != nil {
+			return err
+		}
+	}
+	message, err = addTrailerOptions(message, options, repo)
+	if err != nil {
+		return err
+	}
+	err = metro.CheckMessage(message, repo)
+	if err != nil {
+		return err
+	}
+
+	generated, err := metro.LikelyGenerated(repo)
+	if err != nil {
+		return err
+	}
+	for _, path := range generated {
+		fmt.Println("Warning: " + path + " looks like build output. Use metro ignore add " + path + " to leave it out of commits.")
+	}
+
+	_, allowEmpty := options["allow-empty"]
+	changes, err := metro.CommitChanges(repo, message, allowEmpty)
 	if err != nil {
 		return err
 	}
 
 	fmt.Println("Saved commit to current branch.")
+	printChanges(changes)
 	return nil
 }
 
+// Print a line for each changed file, saying how it changed.
+func printChanges(changes []metro.FileChange) {
+	for _, change := range changes {
+		var verb string
+		switch change.Status {
+		case git.DeltaAdded:
+			verb = "added"
+		case git.DeltaDeleted:
+			verb = "deleted"
+		case git.DeltaRenamed:
+			verb = "renamed"
+		case git.DeltaTypeChange:
+			verb = "changed type"
+		default:
+			verb = "modified"
+		}
+		fmt.Printf("  %-12s %s\n", verb, change.Path)
+	}
+	if len(changes) == 1 {
+		fmt.Println("1 file changed.")
+	} else {
+		fmt.Printf("%d files changed.\n", len(changes))
+	}
+}
+
 func printCommitHelp(_ []string, _ map[string]string) {
-	fmt.Println("Usage: metro commit <message>")
+	fmt.Println("Usage: metro commit [message] [--allow-empty] " + trailerOptionsUsage)
+	fmt.Println("If no message is given, the template set by metro.messageTemplate is opened in your editor.")
 }
 
-var Commit = Command{"commit", "Make a commit", execCommit, printCommitHelp}
+var Commit = Command{"commit", "Make a commit", journaled("commit", execCommit), printCommitHelp}
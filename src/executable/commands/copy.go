@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strings"
+)
+
+func execCopy(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) < 1 {
+		return errors.New("Commit required.")
+	}
+
+	// Each argument is a commit, or a range of commits from..to on the line that to is on.
+	var commits []*git.Commit
+	for _, revision := range positionals {
+		if strings.Contains(revision, "..") {
+			parts := strings.SplitN(revision, "..", 2)
+			rangeCommits, err := metro.CommitsBetween(parts[0], parts[1], repo)
+			if err != nil {
+				return err
+			}
+			commits = append(commits, rangeCommits...)
+			continue
+		}
+		commit, err := metro.GetCommit(revision, repo)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, commit)
+	}
+
+	fmt.Println("Copying:")
+	for _, commit := range commits {
+		fmt.Println("  " + metro.ShortID(commit) + " " + commit.Summary())
+	}
+	conflicts, err := metro.CopyCommits(commits, repo)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Conflicts occurred while copying, please resolve.")
+	} else {
+		fmt.Println("Copied the commits onto the current line.")
+	}
+	return nil
+}
+
+func printCopyHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro copy <commit>...")
+	fmt.Println("       metro copy <from>..<to>")
+	fmt.Println("A range copies the commits after <from>, up to and including <to>.")
+}
+
+var Copy = Command{"copy", "Copy commits from another line onto this one", journaled("copy", execCopy), printCopyHelp}
@@ -24,7 +24,42 @@ func execDelete(repo *git.Repository, positionals []string, options map[string]s
 				return err
 			}
 		}
-		return metro.DeleteCommits(repo, deletes, false)
+		commits, err := metro.CommitsToDelete(repo, deletes)
+		if err != nil {
+			return err
+		}
+		_, preview := options["preview"]
+		if preview {
+			fmt.Println("Would delete:")
+		} else {
+			fmt.Println("Deleting:")
+		}
+		for _, commit := range commits {
+			fmt.Println("  " + metro.ShortID(commit) + " " + commit.Summary())
+		}
+		if preview {
+			return nil
+		}
+
+		if _, force := options["force"]; !force {
+			err = metro.AssertSafeToDelete(commits, repo)
+			if err != nil {
+				return errors.New(err.Error() + "\nUse --force to delete it anyway.")
+			}
+		}
+
+		_, discard := options["discard"]
+		err = metro.DeleteCommits(repo, deletes, discard)
+		if err != nil {
+			return err
+		}
+		if discard {
+			fmt.Println("Deleted the commits and discarded their changes.")
+		} else {
+			fmt.Println("Deleted the commits, their changes are still in your working directory.")
+		}
+		fmt.Println("Use metro undo to bring them back.")
+		return nil
 	}
 	if positionals[0] == "line" {
 		if len(positionals) < 2 {
@@ -34,15 +69,21 @@ func execDelete(repo *git.Repository, positionals []string, options map[string]s
 			return errors.New("Unexpected argument: " + positionals[2])
 		}
 		name := positionals[1]
-		current, err := metro.CurrentBranchName(repo)
+		// Any line can be deleted while viewing a commit.
+		head, err := metro.GetHead(repo)
 		if err != nil {
 			return err
 		}
-		if name == current {
+		if name == head.Line {
 			return errors.New("Can't delete current branch.")
 		}
 
-		return metro.DeleteBranch(name, repo)
+		err = metro.DeleteBranch(name, repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Moved line " + name + " to the trash. Use metro restore line " + name + " to bring it back.")
+		return nil
 	}
 	return nil
 }
@@ -50,13 +91,14 @@ func execDelete(repo *git.Repository, positionals []string, options map[string]s
 func printDeleteHelp(positionals []string, _ map[string]string) {
 	if len(positionals) < 1 || (positionals[0] != "commit" && positionals[0] != "line") {
 		fmt.Println("Usage: metro delete <commit/line>")
+		return
 	}
 	if positionals[0] == "commit" {
-		fmt.Println("Usage: metro delete commit <num>")
+		fmt.Println("Usage: metro delete commit [num] [--discard] [--preview] [--force]")
 	}
 	if positionals[0] == "line" {
 		fmt.Println("Usage: metro delete line <line-name>")
 	}
 }
 
-var Delete = Command{"delete", "Deletes a commit or branch", execDelete, printDeleteHelp}
+var Delete = Command{"delete", "Deletes a commit or branch", journaled("delete", execDelete), printDeleteHelp}
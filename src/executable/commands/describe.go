@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strings"
+)
+
+func execDescribe(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	name, ok := options["line"]
+	if !ok {
+		var err error
+		name, err = metro.CurrentBranchName(repo)
+		if err != nil {
+			return err
+		}
+	}
+	if strings.HasSuffix(name, metro.WipString) || !metro.LineExists(name, repo) {
+		return errors.New("No line called " + name + ".")
+	}
+	metadata, err := metro.GetLineMetadata(name, repo)
+	if err != nil {
+		return err
+	}
+
+	// Only the given fields are changed, without any the metadata is just shown.
+	changed := false
+	if len(positionals) == 1 {
+		metadata.Description = positionals[0]
+		changed = true
+	}
+	if owner, ok := options["owner"]; ok {
+		metadata.Owner = owner
+		changed = true
+	}
+	if _, ok := options["issue"]; ok {
+		metadata.Issues = nil
+		for _, issue := range optionValues(options, "issue") {
+			if issue != "" {
+				metadata.Issues = append(metadata.Issues, issue)
+			}
+		}
+		changed = true
+	}
+	if status, ok := options["status"]; ok {
+		metadata.Status = status
+		changed = true
+	}
+
+	if changed {
+		err = metro.SetLineMetadata(name, metadata, repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Updated the description of " + name + ".")
+		return nil
+	}
+	if metadata.Empty() {
+		fmt.Println("Line " + name + " has no description.")
+		return nil
+	}
+	fmt.Println(name)
+	printLineMetadata(metadata, "  ")
+	return nil
+}
+
+func printDescribeHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro describe [description] [--owner <name>] [--issue <id>]... [--status <active/review/done>] [--line <line>]")
+	fmt.Println("Sets what the current line, or the line given with --line, is for. Without any changes its description is shown.")
+	fmt.Println("Giving an empty value clears it.")
+}
+
+var Describe = Command{"describe", "Describe what a line is for", journaled("describe", execDescribe), printDescribeHelp}
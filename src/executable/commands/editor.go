@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Open the user's editor to write a commit message, starting with the given text.
+// Lines starting with # are removed from the result.
+func editMessage(initial string, repo *git.Repository) (string, error) {
+	file := filepath.Join(repo.Path(), "COMMIT_EDITMSG")
+	initial += "\n# Write the commit message above. Lines starting with # will be ignored.\n"
+	err := ioutil.WriteFile(file, []byte(initial), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	editor, err := userEditor(repo)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", file)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", errors.New("Editor failed: " + err.Error())
+	}
+
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(dat), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	message := strings.TrimSpace(strings.Join(lines, "\n"))
+	if message == "" {
+		return "", errors.New("Empty message, nothing was committed.")
+	}
+	return message, nil
+}
+
+// Find the editor the user prefers, the same way git does.
+func userEditor(repo *git.Repository) (string, error) {
+	if editor := os.Getenv("GIT_EDITOR"); editor != "" {
+		return editor, nil
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	if editor, err := config.LookupString("core.editor"); err == nil && editor != "" {
+		return editor, nil
+	}
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(variable); editor != "" {
+			return editor, nil
+		}
+	}
+	return "vi", nil
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+	"strings"
+)
+
+func execHistory(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	limit := 10
+	if len(positionals) == 1 {
+		var err error
+		limit, err = strconv.Atoi(positionals[0])
+		if err != nil || limit < 0 {
+			return errors.New("Invalid number of commits: " + positionals[0])
+		}
+	}
+
+	commits, err := metro.LineHistory("HEAD", limit, repo)
+	if err != nil {
+		return err
+	}
+	marks, err := metro.MarksByCommit(repo)
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		authors := []string{commit.Author().Name}
+		for _, coAuthor := range metro.CoAuthors(commit.Message()) {
+			authors = append(authors, personName(coAuthor))
+		}
+		marked := ""
+		if names := marks[commit.Id().String()]; len(names) > 0 {
+			marked = " [" + strings.Join(names, ", ") + "]"
+		}
+		fmt.Printf("%s%s %s (%s)\n", metro.ShortID(commit), marked, commit.Summary(), strings.Join(authors, ", "))
+	}
+	return nil
+}
+
+// The name part of a "Name <email>" string.
+func personName(person string) string {
+	index := strings.Index(person, " <")
+	if index < 0 {
+		return person
+	}
+	return person[:index]
+}
+
+func printHistoryHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro history [num]")
+}
+
+var History = Command{"history", "List the latest commits on the current line", execHistory, printHistoryHelp}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+)
+
+func execIgnore(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) < 1 || positionals[0] == "list" {
+		if len(positionals) > 1 {
+			return errors.New("Unexpected argument: " + positionals[1])
+		}
+		rules, err := metro.IgnoreRules(repo)
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			fmt.Println("No ignore rules.")
+		}
+		for _, rule := range rules {
+			fmt.Println(rule.Pattern)
+		}
+		return nil
+	}
+
+	switch positionals[0] {
+	case "add":
+		if len(positionals) < 2 {
+			return errors.New("Pattern required.")
+		}
+		for _, pattern := range positionals[1:] {
+			err := metro.AddIgnoreRule(pattern, repo)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Ignoring " + pattern + ".")
+		}
+
+		if _, untrack := options["untrack"]; untrack {
+			paths, err := metro.UntrackIgnored(repo)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				fmt.Println("Untracked " + path)
+			}
+			if len(paths) > 0 {
+				fmt.Println("The untracked files will be removed from the line on your next commit.")
+			}
+		} else {
+			paths, err := metro.TrackedIgnored(repo)
+			if err != nil {
+				return err
+			}
+			if len(paths) > 0 {
+				fmt.Println(strconv.Itoa(len(paths)) + " committed files match an ignore rule and will keep being committed.")
+				fmt.Println("Use --untrack to stop committing them.")
+			}
+		}
+		return nil
+	case "remove":
+		if len(positionals) < 2 {
+			return errors.New("Pattern required.")
+		}
+		for _, pattern := range positionals[1:] {
+			err := metro.RemoveIgnoreRule(pattern, repo)
+			if err != nil {
+				return err
+			}
+			fmt.Println("No longer ignoring " + pattern + ".")
+		}
+		return nil
+	case "why":
+		if len(positionals) < 2 {
+			return errors.New("Path required.")
+		}
+		if len(positionals) > 2 {
+			return errors.New("Unexpected argument: " + positionals[2])
+		}
+		path := positionals[1]
+		rule, err := metro.ExplainIgnored(path, repo)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			fmt.Println(path + " is not ignored.")
+		} else {
+			fmt.Printf("%s is ignored by %s in %s, line %d.\n", path, rule.Pattern, rule.Source, rule.Line)
+		}
+		return nil
+	}
+	return errors.New("Incorrect paramater.")
+}
+
+func printIgnoreHelp(positionals []string, _ map[string]string) {
+	if len(positionals) < 1 {
+		fmt.Println("Usage: metro ignore <list/add/remove/why>")
+		return
+	}
+	switch positionals[0] {
+	case "list":
+		fmt.Println("Usage: metro ignore list")
+	case "add":
+		fmt.Println("Usage: metro ignore add <pattern>... [--untrack]")
+	case "remove":
+		fmt.Println("Usage: metro ignore remove <pattern>...")
+	case "why":
+		fmt.Println("Usage: metro ignore why <path>")
+	default:
+		fmt.Println("Usage: metro ignore <list/add/remove/why>")
+	}
+}
+
+var Ignore = Command{"ignore", "Manage which files are left out of commits", execIgnore, printIgnoreHelp}
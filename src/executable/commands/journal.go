@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strings"
+)
+
+// Wrap a command's Execute function so that the changes it makes are recorded in the oplog,
+// allowing them to be undone. HEAD is first put back on a line if an earlier switch was interrupted.
+func journaled(name string, execute func(*git.Repository, []string, map[string]string) error) func(*git.Repository, []string, map[string]string) error {
+	return func(repo *git.Repository, positionals []string, options map[string]string) error {
+		if repo == nil {
+			return execute(repo, positionals, options)
+		}
+		recoverHead(repo)
+
+		before, err := metro.TakeSnapshot(repo)
+		if err != nil {
+			fmt.Println("Warning: this operation can't be undone: " + err.Error())
+			return execute(repo, positionals, options)
+		}
+
+		// Operations that fail partway through are recorded too, so that what they did can be undone.
+		execErr := execute(repo, positionals, options)
+		err = metro.RecordOperation(strings.TrimSpace(name+" "+strings.Join(positionals, " ")), before, repo)
+		if execErr != nil {
+			return execErr
+		}
+		if err != nil {
+			fmt.Println("Warning: this operation can't be undone: " + err.Error())
+		}
+		return nil
+	}
+}
+
+// Put HEAD back on a line if it was left on the line's WIP, recording this as its own operation in the oplog.
+// Failing to recover isn't fatal, the command is left to explain what is wrong if it needs a line.
+func recoverHead(repo *git.Repository) {
+	head, err := metro.GetHead(repo)
+	if err != nil || head.State != metro.HeadOnWip {
+		return
+	}
+
+	before, err := metro.TakeSnapshot(repo)
+	if err != nil {
+		fmt.Println("Warning: recovering the head can't be undone: " + err.Error())
+	}
+	line, err := metro.RecoverHead(repo)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	fmt.Println("Recovered from an interrupted switch, you are back on line " + line + ".")
+	if before == nil {
+		return
+	}
+	err = metro.RecordOperation("recover head", before, repo)
+	if err != nil {
+		fmt.Println("Warning: recovering the head can't be undone: " + err.Error())
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-func execLine(repo *git.Repository, positionals []string, _ map[string]string) error {
+func execLine(repo *git.Repository, positionals []string, options map[string]string) error {
 	if len(positionals) < 1 {
 		return errors.New("Line name required.")
 	}
@@ -21,17 +21,32 @@ func execLine(repo *git.Repository, positionals []string, _ map[string]string) e
 		return errors.New("Line name can't end in " + metro.WipString)
 	}
 
-	_, err := metro.CreateBranch(name, repo)
+	from, ok := options["from"]
+	if !ok {
+		from = "HEAD"
+	}
+	_, err := metro.CreateBranchFrom(name, from, repo)
 	if err != nil {
 		return err
 	}
+	if ok {
+		fmt.Println("Created line " + name + " from " + from + ".")
+	} else {
+		fmt.Println("Created line " + name + ".")
+	}
 
-	fmt.Println("Created line " + name + ".")
+	if _, switchLine := options["switch"]; switchLine {
+		err = metro.SwitchBranch(name, repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Switched to line " + name + ".")
+	}
 	return nil
 }
 
 func printLineHelp(_ []string, _ map[string]string) {
-	fmt.Println("Usage: metro line <name>")
+	fmt.Println("Usage: metro line <name> [--from <commit/line>] [--switch]")
 }
 
-var Line = Command{"line", "Create a new line", execLine, printLineHelp}
+var Line = Command{"line", "Create a new line", journaled("line", execLine), printLineHelp}
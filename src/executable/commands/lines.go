@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func execLines(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) > 0 {
+		return errors.New("Unexpected argument: " + positionals[0])
+	}
+	// Only pruning changes anything, so listing isn't journaled.
+	if _, prune := options["prune"]; prune {
+		return journaled("prune lines", pruneLines)(repo, positionals, options)
+	}
+	names, err := metro.Lines(repo)
+	if err != nil {
+		return err
+	}
+	current, err := metro.CurrentBranchName(repo)
+	if err != nil {
+		current = ""
+	}
+	metadata, err := metro.AllLineMetadata(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		marker := "  "
+		if name == current {
+			marker = "* "
+		}
+		fmt.Println(marker + name)
+		printLineMetadata(metadata[name], "    ")
+	}
+	return nil
+}
+
+// Offer to delete or archive lines that have been absorbed into another line, or have had no commits for a while.
+// The lines are only listed unless the user confirms with --confirm.
+func pruneLines(repo *git.Repository, _ []string, options map[string]string) error {
+	target := options["to"]
+	staleDays := 0
+	if value, ok := options["stale"]; ok {
+		var err error
+		staleDays, err = strconv.Atoi(value)
+		if err != nil || staleDays < 1 {
+			return errors.New("Invalid number of days: " + value)
+		}
+	}
+	candidates, err := metro.PruneCandidates(target, staleDays, repo)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Println("No lines to prune.")
+		return nil
+	}
+
+	_, confirmed := options["confirm"]
+	_, archive := options["archive"]
+	switch {
+	case !confirmed:
+		fmt.Println("Lines that can be pruned:")
+	case archive:
+		fmt.Println("Archiving:")
+	default:
+		fmt.Println("Deleting:")
+	}
+	for _, candidate := range candidates {
+		if candidate.AbsorbedInto != "" {
+			fmt.Println("  " + candidate.Name + " (absorbed into " + candidate.AbsorbedInto + ")")
+		} else {
+			fmt.Println("  " + candidate.Name + " (last commit " + daysAgo(candidate.LastCommit) + ")")
+		}
+	}
+	if !confirmed {
+		if archive {
+			fmt.Println("Run again with --confirm to archive them.")
+		} else {
+			fmt.Println("Run again with --confirm to delete them, or with --archive --confirm to archive them.")
+		}
+		return nil
+	}
+
+	for _, candidate := range candidates {
+		if archive {
+			err = metro.ArchiveBranch(candidate.Name, repo)
+		} else {
+			err = metro.DeleteBranch(candidate.Name, repo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("Use metro restore line <name> to bring a line back.")
+	return nil
+}
+
+// Describe how long ago a time was in days.
+func daysAgo(t time.Time) string {
+	days := int(time.Since(t).Hours() / 24)
+	if days == 1 {
+		return "1 day ago"
+	}
+	return strconv.Itoa(days) + " days ago"
+}
+
+// Print the metadata of a line, one field per line, leaving out fields that aren't set.
+func printLineMetadata(metadata metro.LineMetadata, indent string) {
+	if metadata.Description != "" {
+		fmt.Println(indent + metadata.Description)
+	}
+	if metadata.Status != "" {
+		fmt.Println(indent + "Status: " + metadata.Status)
+	}
+	if metadata.Owner != "" {
+		fmt.Println(indent + "Owner: " + metadata.Owner)
+	}
+	if len(metadata.Issues) > 0 {
+		fmt.Println(indent + "Issues: " + strings.Join(metadata.Issues, ", "))
+	}
+}
+
+func printLinesHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro lines")
+	fmt.Println("       metro lines --prune [--to <line>] [--stale <days>] [--archive] [--confirm]")
+	fmt.Println("--prune lists lines absorbed into the line given with --to, or with no commits for --stale days.")
+	fmt.Println("--confirm deletes them. With --archive they are kept in the archive instead of the trash, where they never expire.")
+}
+
+var Lines = Command{"lines", "List all lines and what they are for", execLines, printLinesHelp}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strings"
+)
+
+func execMark(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) < 1 {
+		return errors.New("Mark name required.")
+	}
+
+	switch positionals[0] {
+	case "list":
+		if len(positionals) > 1 {
+			return errors.New("Unexpected argument: " + positionals[1])
+		}
+		marks, err := metro.Marks(repo)
+		if err != nil {
+			return err
+		}
+		if len(marks) == 0 {
+			fmt.Println("No marks.")
+		}
+		for _, mark := range marks {
+			commit, err := repo.LookupCommit(mark.Commit)
+			if err != nil {
+				return err
+			}
+			line := mark.Name + " " + metro.ShortID(commit)
+			if summary := strings.TrimSpace(strings.SplitN(mark.Message, "\n", 2)[0]); summary != "" && summary != mark.Name {
+				line += " " + summary
+			}
+			if mark.Signed {
+				line += " (signed)"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	case "delete":
+		if len(positionals) < 2 {
+			return errors.New("Mark name required.")
+		}
+		for _, name := range positionals[1:] {
+			err := metro.DeleteMark(name, repo)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Deleted mark " + name + ".")
+		}
+		return nil
+	}
+
+	if len(positionals) > 3 {
+		return errors.New("Unexpected argument: " + positionals[3])
+	}
+	name := positionals[0]
+	revision := "HEAD"
+	if len(positionals) > 1 {
+		revision = positionals[1]
+	}
+	message := ""
+	if len(positionals) > 2 {
+		message = positionals[2]
+	}
+	_, sign := options["sign"]
+
+	commit, err := metro.CreateMark(name, revision, message, sign, repo)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Marked " + metro.ShortID(commit) + " " + commit.Summary() + " as " + name + ".")
+	return nil
+}
+
+func printMarkHelp(positionals []string, _ map[string]string) {
+	if len(positionals) > 0 && positionals[0] == "list" {
+		fmt.Println("Usage: metro mark list")
+		return
+	}
+	if len(positionals) > 0 && positionals[0] == "delete" {
+		fmt.Println("Usage: metro mark delete <name>...")
+		return
+	}
+	fmt.Println("Usage: metro mark <name> [commit] [message] [--sign]")
+	fmt.Println("       metro mark <list/delete>")
+	fmt.Println("--sign signs the mark with your GPG key, set with git config user.signingKey.")
+}
+
+var Mark = Command{"mark", "Name a commit, such as a release", journaled("mark", execMark), printMarkHelp}
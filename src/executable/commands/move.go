@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+	"strings"
+)
+
+func execMove(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) < 1 {
+		return errors.New("Number of commits or range required.")
+	}
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	line, ok := options["to"]
+	if !ok {
+		return errors.New("Line to move to required, use --to <line>.")
+	}
+
+	// Either the last n commits, or a range of commits from..to on the current line.
+	var commits []*git.Commit
+	if strings.Contains(positionals[0], "..") {
+		parts := strings.SplitN(positionals[0], "..", 2)
+		var err error
+		commits, err = metro.CommitRange(parts[0], parts[1], repo)
+		if err != nil {
+			return err
+		}
+	} else {
+		count, err := strconv.Atoi(positionals[0])
+		if err != nil {
+			return errors.New("Invalid number of commits: " + positionals[0])
+		}
+		newestFirst, err := metro.CommitsToDelete(repo, count)
+		if err != nil {
+			return err
+		}
+		for i := len(newestFirst) - 1; i >= 0; i-- {
+			commits = append(commits, newestFirst[i])
+		}
+	}
+
+	fmt.Println("Moving to " + line + ":")
+	for _, commit := range commits {
+		fmt.Println("  " + metro.ShortID(commit) + " " + commit.Summary())
+	}
+	if _, force := options["force"]; !force {
+		err := metro.AssertSafeToDelete(commits, repo)
+		if err != nil {
+			return errors.New(err.Error() + "\nUse --force to move it anyway.")
+		}
+	}
+
+	conflicts, err := metro.MoveCommits(commits, line, repo)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Moved the commits, but conflicts occurred replaying the commits after them, please resolve.")
+	} else {
+		fmt.Println("Moved the commits to " + line + ".")
+	}
+	return nil
+}
+
+func printMoveHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro move <num> --to <line> [--force]")
+	fmt.Println("       metro move <from>..<to> --to <line> [--force]")
+	fmt.Println("A range moves the commits after <from>, up to and including <to>.")
+}
+
+var Move = Command{"move", "Move commits to another line", journaled("move", execMove), printMoveHelp}
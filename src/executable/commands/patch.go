@@ -8,8 +8,15 @@ import (
 )
 
 func execPatch(repo *git.Repository, positionals []string, options map[string]string) error {
+	revision, patchEarlier := options["commit"]
+	if !patchEarlier {
+		revision = "HEAD"
+	}
+
 	// Uses existing message as default
-	commit, err := metro.GetCommit("HEAD", repo)
+	// Only messages written by the user are checked against the message rules,
+	// so commits with older or Metro's own messages can still be patched.
+	commit, err := metro.GetCommit(revision, repo)
 	if err != nil {
 		return err
 	}
@@ -23,17 +30,40 @@ func execPatch(repo *git.Repository, positionals []string, options map[string]st
 		return errors.New("Unexpected argument: " + positionals[1])
 	}
 
-	err = metro.Patch(repo, message)
+	message, err = addTrailerOptions(message, options, repo)
 	if err != nil {
 		return err
 	}
+	if len(positionals) == 1 {
+		err = metro.CheckMessage(message, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !patchEarlier {
+		err = metro.Patch(repo, message)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Patched commit.")
+		return nil
+	}
 
-	fmt.Println("Patched commit.")
+	conflicts, err := metro.PatchCommit(repo, revision, message)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Patched commit, but conflicts occurred replaying the commits after it, please resolve.")
+	} else {
+		fmt.Println("Patched commit and replayed the commits after it.")
+	}
 	return nil
 }
 
 func printPatchHelp(_ []string, _ map[string]string) {
-	fmt.Println("Usage: metro patch <message>")
+	fmt.Println("Usage: metro patch [message] [--commit <commit>] " + trailerOptionsUsage)
 }
 
-var Patch = Command{"patch", "Will patch the last commit with the current work", execPatch, printPatchHelp}
+var Patch = Command{"patch", "Will patch the last commit with the current work", journaled("patch", execPatch), printPatchHelp}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+func execRename(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) < 1 || positionals[0] != "line" {
+		return errors.New("Incorrect paramater.")
+	}
+	if len(positionals) < 3 {
+		return errors.New("Old and new line names required.")
+	}
+	if len(positionals) > 3 {
+		return errors.New("Unexpected argument: " + positionals[3])
+	}
+	oldName := positionals[1]
+	newName := positionals[2]
+
+	err := metro.RenameBranch(oldName, newName, repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Renamed line " + oldName + " to " + newName + ".")
+	return nil
+}
+
+func printRenameHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro rename line <old-name> <new-name>")
+}
+
+var Rename = Command{"rename", "Rename a line", journaled("rename", execRename), printRenameHelp}
@@ -8,12 +8,38 @@ import (
 )
 
 func execResolve(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	if metro.ReplayOngoing(repo) {
+		return resolveReplay(repo, positionals, options)
+	}
 	merging := metro.MergeOngoing(repo)
 	if !merging {
 		return errors.New("You can only resolve conflicts while absorbing.")
 	}
 
-	err := metro.Resolve(repo)
+	// Metro's own absorb message is used unless another is given.
+	// Only messages written by the user are checked against the message rules.
+	message, err := metro.MergeMessage(repo)
+	if err != nil {
+		return err
+	}
+	if len(positionals) == 1 {
+		message = positionals[0]
+	}
+	message, err = addTrailerOptions(message, options, repo)
+	if err != nil {
+		return err
+	}
+	if len(positionals) == 1 {
+		err = metro.CheckMessage(message, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = metro.Resolve(repo, message)
 	if err != nil {
 		return err
 	}
@@ -27,8 +53,41 @@ func execResolve(repo *git.Repository, positionals []string, options map[string]
 	return nil
 }
 
+// Commit the resolved conflicts of a replayed commit and carry on replaying.
+func resolveReplay(repo *git.Repository, positionals []string, options map[string]string) error {
+	// The replayed commit's own message is used unless another is given.
+	message := ""
+	if len(positionals) == 1 {
+		var err error
+		message, err = addTrailerOptions(positionals[0], options, repo)
+		if err != nil {
+			return err
+		}
+		err = metro.CheckMessage(message, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	operation, err := metro.ReplayOperation(repo)
+	if err != nil {
+		return err
+	}
+	conflicts, err := metro.ContinueReplay(repo, message)
+	if err != nil {
+		return err
+	}
+
+	if conflicts {
+		fmt.Println("Conflicts occurred in the next commit, please resolve.")
+	} else {
+		fmt.Println("Finished " + operation + ".")
+	}
+	return nil
+}
+
 func printResolveHelp(_ []string, _ map[string]string) {
-	fmt.Println("Usage: metro resolve")
+	fmt.Println("Usage: metro resolve [message] " + trailerOptionsUsage)
 }
 
-var Resolve = Command{"resolve", "Commit resolved conflicts after absorb", execResolve, printResolveHelp}
+var Resolve = Command{"resolve", "Commit resolved conflicts after absorb", journaled("resolve", execResolve), printResolveHelp}
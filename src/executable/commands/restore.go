@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+func execRestore(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) < 1 || positionals[0] != "line" {
+		return errors.New("Incorrect paramater.")
+	}
+	if len(positionals) < 2 {
+		return errors.New("Line name required.")
+	}
+	if len(positionals) > 2 {
+		return errors.New("Unexpected argument: " + positionals[2])
+	}
+	name := positionals[1]
+
+	err := metro.RestoreBranch(name, repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Restored line " + name + ".")
+	return nil
+}
+
+func printRestoreHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro restore line <line-name>")
+}
+
+var Restore = Command{"restore", "Bring back a deleted line", journaled("restore", execRestore), printRestoreHelp}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+)
+
+func execRevert(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) < 1 {
+		return errors.New("Commit required.")
+	}
+	if len(positionals) > 2 {
+		return errors.New("Unexpected argument: " + positionals[2])
+	}
+	message := ""
+	if len(positionals) == 2 {
+		message = positionals[1]
+		err := metro.CheckMessage(message, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Absorbs are undone relative to the line they were made on unless told otherwise.
+	mainline := 1
+	if value, ok := options["mainline"]; ok {
+		var err error
+		mainline, err = strconv.Atoi(value)
+		if err != nil {
+			return errors.New("Invalid mainline: " + value)
+		}
+	}
+
+	conflicts, err := metro.Revert(positionals[0], mainline, message, repo)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Conflicts occurred while reverting, please resolve.")
+	} else {
+		fmt.Println("Reverted commit " + positionals[0] + ".")
+	}
+	return nil
+}
+
+func printRevertHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro revert <commit> [message] [--mainline <num>]")
+	fmt.Println("Adds a commit undoing the changes of <commit>.")
+	fmt.Println("For an absorb, --mainline picks which parent to go back to, 1 being the line it was absorbed into.")
+}
+
+var Revert = Command{"revert", "Add a commit undoing an earlier commit", journaled("revert", execRevert), printRevertHelp}
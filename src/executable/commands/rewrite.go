@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"metro"
+	"strconv"
+)
+
+func execRewrite(repo *git.Repository, positionals []string, options map[string]string) error {
+	_, continueRewrite := options["continue"]
+	_, abort := options["abort"]
+	count, plan := options["plan"]
+
+	if continueRewrite || abort {
+		if len(positionals) > 1 || (abort && len(positionals) > 0) {
+			return errors.New("Unexpected argument: " + positionals[len(positionals)-1])
+		}
+		if !metro.ReplayOngoing(repo) {
+			return errors.New("There is no rewrite to continue.")
+		}
+		if abort {
+			err := metro.AbortReplay(repo)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Aborted, the line is back as it was.")
+			return nil
+		}
+		return resolveReplay(repo, positionals, options)
+	}
+
+	if plan {
+		if len(positionals) > 0 {
+			return errors.New("Unexpected argument: " + positionals[0])
+		}
+		commitsBack, err := strconv.Atoi(count)
+		if err != nil || commitsBack < 1 {
+			return errors.New("Invalid number of commits: " + count)
+		}
+		template, err := metro.RewritePlan(commitsBack, repo)
+		if err != nil {
+			return err
+		}
+		fmt.Print(template)
+		return nil
+	}
+
+	if len(positionals) < 1 {
+		return errors.New("Plan file required.")
+	}
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	dat, err := ioutil.ReadFile(positionals[0])
+	if err != nil {
+		return err
+	}
+	steps, err := metro.ParseRewritePlan(string(dat), repo)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := metro.Rewrite(steps, repo)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Conflicts occurred while rewriting, please resolve.")
+		fmt.Println("Run metro rewrite --continue when you are done, or metro rewrite --abort to give up.")
+	} else {
+		fmt.Println("Finished rewrite.")
+	}
+	return nil
+}
+
+func printRewriteHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro rewrite <plan-file>")
+	fmt.Println("       metro rewrite --plan <num>")
+	fmt.Println("       metro rewrite --continue [message]")
+	fmt.Println("       metro rewrite --abort")
+	fmt.Println("--plan prints a plan for the last <num> commits to edit and save as a plan file.")
+	fmt.Println("Each line of a plan is pick, reword, squash or drop followed by a commit, oldest first.")
+}
+
+var Rewrite = Command{"rewrite", "Rewrite the line's history from a plan", journaled("rewrite", execRewrite), printRewriteHelp}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strings"
+)
+
+func execShow(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	revision := "HEAD"
+	if len(positionals) == 1 {
+		revision = positionals[0]
+	}
+
+	commit, err := metro.GetCommit(revision, repo)
+	if err != nil {
+		return err
+	}
+	body, trailers := metro.ParseTrailers(commit.Message())
+
+	fmt.Println("Commit " + commit.Id().String())
+	author := commit.Author()
+	fmt.Println("Author: " + author.Name + " <" + author.Email + ">")
+	for _, trailer := range trailers {
+		if strings.EqualFold(trailer.Key, metro.CoAuthorKey) {
+			fmt.Println("Co-author: " + trailer.Value)
+		}
+	}
+	fmt.Println("Date: " + author.When.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	if commit.ParentCount() > 1 {
+		var parents []string
+		for i := uint(0); i < commit.ParentCount(); i++ {
+			parents = append(parents, metro.ShortID(commit.Parent(i)))
+		}
+		fmt.Println("Absorb of: " + strings.Join(parents, " "))
+	}
+
+	fmt.Println()
+	for _, line := range strings.Split(body, "\n") {
+		fmt.Println("    " + line)
+	}
+	var others []string
+	for _, trailer := range trailers {
+		if !strings.EqualFold(trailer.Key, metro.CoAuthorKey) {
+			others = append(others, trailer.String())
+		}
+	}
+	if len(others) > 0 {
+		fmt.Println()
+		for _, trailer := range others {
+			fmt.Println("    " + trailer)
+		}
+	}
+
+	if commit.ParentCount() > 0 {
+		changes, err := metro.ChangedFiles(commit.Parent(0).Id().String(), commit.Id().String(), repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		printChanges(changes)
+	}
+	return nil
+}
+
+func printShowHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro show [commit]")
+}
+
+var Show = Command{"show", "Show the details of a commit", execShow, printShowHelp}
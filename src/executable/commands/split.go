@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+	"strings"
+)
+
+func execSplit(repo *git.Repository, positionals []string, options map[string]string) error {
+	revision, ok := options["commit"]
+	if !ok {
+		revision = "HEAD"
+	}
+
+	// Without any groups, show the changes that can be split up.
+	if len(positionals) == 0 {
+		files, err := metro.SplitCandidates(revision, repo)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fmt.Println(file.Path)
+			for i, hunk := range file.Hunks {
+				fmt.Println("  " + strconv.Itoa(i+1) + ": " + hunk)
+			}
+		}
+		fmt.Println("Split the commit with metro split <group>..., where each group is a comma separated list of paths.")
+		fmt.Println("Use <file>:<num> to take a single hunk of a file.")
+		return nil
+	}
+
+	var groups [][]string
+	for _, group := range positionals {
+		groups = append(groups, strings.Split(group, ","))
+	}
+
+	conflicts, err := metro.Split(repo, revision, groups)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		fmt.Println("Split commit, but conflicts occurred replaying the commits after it, please resolve.")
+	} else {
+		fmt.Println("Split commit.")
+	}
+	return nil
+}
+
+func printSplitHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro split [group]... [--commit <commit>]")
+	fmt.Println("Each group is a comma separated list of files, directories or <file>:<num> hunks,")
+	fmt.Println("and becomes its own commit. Changes not in any group go into a final commit.")
+	fmt.Println("Without any groups, lists the files and hunks changed by the commit.")
+}
+
+var Split = Command{"split", "Split a commit into several commits", journaled("split", execSplit), printSplitHelp}
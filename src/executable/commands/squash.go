@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+	"strconv"
+)
+
+func execSquash(repo *git.Repository, positionals []string, options map[string]string) error {
+	var count int
+	var message string
+	if since, ok := options["since"]; ok {
+		if len(positionals) > 1 {
+			return errors.New("Unexpected argument: " + positionals[1])
+		}
+		if len(positionals) == 1 {
+			message = positionals[0]
+		}
+		var err error
+		count, err = metro.CountCommitsSince(since, repo)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(positionals) < 1 {
+			return errors.New("Number of commits or --since required.")
+		}
+		if len(positionals) > 2 {
+			return errors.New("Unexpected argument: " + positionals[2])
+		}
+		var err error
+		count, err = strconv.Atoi(positionals[0])
+		if err != nil {
+			return errors.New("Invalid number of commits: " + positionals[0])
+		}
+		if len(positionals) == 2 {
+			message = positionals[1]
+		}
+	}
+
+	if message != "" {
+		err := metro.CheckMessage(message, repo)
+		if err != nil {
+			return err
+		}
+	}
+	if _, force := options["force"]; !force && count > 1 {
+		commits, err := metro.CommitsToDelete(repo, count)
+		if err != nil {
+			return err
+		}
+		err = metro.AssertSafeToDelete(commits, repo)
+		if err != nil {
+			return errors.New(err.Error() + "\nUse --force to squash it anyway.")
+		}
+	}
+	err := metro.Squash(repo, count, message)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Squashed %d commits into one.\n", count)
+	if message == "" {
+		fmt.Println("Their messages were combined, use metro patch <message> to change it.")
+	}
+	return nil
+}
+
+func printSquashHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro squash <num> [message] [--force]")
+	fmt.Println("       metro squash --since <line> [message] [--force]")
+	fmt.Println("Without a message, the messages of the squashed commits are combined.")
+	fmt.Println("Commits that have been synced are only squashed with --force. Absorbs can't be squashed.")
+}
+
+var Squash = Command{"squash", "Combine the latest commits into one", journaled("squash", execSquash), printSquashHelp}
@@ -1,12 +1,54 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"github.com/libgit2/git2go"
+	"metro"
 )
 
-// TODO: Make this print current branch
-func execStatus(_ *git.Repository, _ []string, _ map[string]string) error {
+func execStatus(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) > 0 {
+		return errors.New("Unexpected argument: " + positionals[0])
+	}
+	head, err := metro.GetHead(repo)
+	if err != nil {
+		return err
+	}
+	switch head.State {
+	case metro.HeadViewing:
+		commit, err := metro.GetCommit("HEAD", repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Viewing commit " + metro.ShortID(commit) + " " + commit.Summary() + ", which is read-only.")
+		fmt.Println("Use metro switch <line> to go back to a line, or metro line <name> --switch to start one here.")
+		return nil
+	case metro.HeadElsewhere:
+		fmt.Println("HEAD points to " + head.Ref + ", which isn't a line.")
+		fmt.Println("Use metro switch <line> to go back to a line.")
+		return nil
+	case metro.HeadUnborn:
+		fmt.Println("On line " + head.Line + ", which has no commits yet.")
+	default:
+		fmt.Println("On line " + head.Line + ".")
+	}
+	current := head.Line
+	metadata, err := metro.GetLineMetadata(current, repo)
+	if err != nil {
+		return err
+	}
+	printLineMetadata(metadata, "  ")
+
+	if metro.ReplayOngoing(repo) {
+		operation, err := metro.ReplayOperation(repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Conflicts occurred during " + operation + ", resolve them and run metro resolve.")
+	} else if metro.MergeOngoing(repo) {
+		fmt.Println("Absorbing, resolve the conflicts and run metro resolve.")
+	}
 	return nil
 }
 
@@ -7,26 +7,62 @@ import (
 	"metro"
 )
 
-func execSwitch(repo *git.Repository, positionals []string, _ map[string]string) error {
-	if len(positionals) < 1 {
-		return errors.New("Branch name required.")
-	}
+func execSwitch(repo *git.Repository, positionals []string, options map[string]string) error {
 	if len(positionals) > 1 {
 		return errors.New("Unexpected argument: " + positionals[1])
 	}
-	name := positionals[0]
+	date, at := options["at"]
+	if len(positionals) < 1 && !at {
+		return errors.New("Branch name required.")
+	}
+
+	// Anything other than a line is viewed read-only.
+	var revision string
+	if at {
+		from := "HEAD"
+		if len(positionals) == 1 {
+			from = positionals[0]
+		}
+		commit, err := metro.CommitAt(from, date, repo)
+		if err != nil {
+			return err
+		}
+		revision = commit.Id().String()
+	} else {
+		name := positionals[0]
+		line, err := metro.ResolveLine(name, repo)
+		if err != nil {
+			return err
+		}
+		if line != "" {
+			err = metro.SwitchBranch(line, repo)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Switched to branch " + line + ".")
+			return nil
+		}
+		revision = name
+	}
 
-	err := metro.SwitchBranch(name, repo)
+	err := metro.SwitchToCommit(revision, repo)
 	if err != nil {
 		return err
 	}
-
-	fmt.Println("Switched to branch " + name + ".")
-	return err
+	commit, err := metro.GetCommit("HEAD", repo)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Viewing commit " + metro.ShortID(commit) + " " + commit.Summary() + ", which is read-only.")
+	fmt.Println("Use metro switch <line> to go back to a line.")
+	return nil
 }
 
 func printSwitchHelp(_ []string, _ map[string]string) {
 	fmt.Println("Usage: metro switch <line>")
+	fmt.Println("       metro switch <commit>")
+	fmt.Println("       metro switch [line] --at <date>")
+	fmt.Println("Switching to a commit, or to the last commit on a line at a date, views it read-only.")
 }
 
-var Switch = Command{"switch", "Switch to a different line", execSwitch, printSwitchHelp}
+var Switch = Command{"switch", "Switch to a different line", journaled("switch", execSwitch), printSwitchHelp}
@@ -1,17 +1,47 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	git "github.com/libgit2/git2go"
+	"metro"
 )
 
-func execSync(_ *git.Repository, positionals []string, options map[string]string) error {
-	fmt.Println(options["timeout"])
+func execSync(repo *git.Repository, positionals []string, options map[string]string) error {
+	// A forced sync could overwrite the history of the line on the remote.
+	if _, force := options["force"]; force {
+		head, err := metro.GetHead(repo)
+		if err != nil {
+			return err
+		}
+		current := head.Line
+		protected, err := metro.IsProtected(current, repo)
+		if err != nil {
+			return err
+		}
+		if protected {
+			return errors.New("Line " + current + " is protected, it can't be force synced.")
+		}
+	}
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+	remote := metro.DefaultRemote
+	if len(positionals) == 1 {
+		remote = positionals[0]
+	}
+
+	err := metro.SyncShared(remote, repo)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Synced line metadata and marks with " + remote + ".")
 	return nil
 }
 
 func printSyncHelp(_ []string, _ map[string]string) {
-	fmt.Printf("Usage: metro sync <up | down | <url>>")
+	fmt.Println("Usage: metro sync [remote]")
+	fmt.Println("Shares line metadata and marks with the remote, origin unless another is given.")
 }
 
-var Sync = Command{"sync", "Sync with remote repo or something like that", execSync, printSyncHelp}
+var Sync = Command{"sync", "Sync with remote repo or something like that", journaled("sync", execSync), printSyncHelp}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+// Options for adding trailers to a commit message, shared by the commands that make commits.
+const trailerOptionsUsage = "[--co-author \"Name <email>\"]... [--trailer key=value]... [--signoff]"
+
+// Add the trailers requested by the --co-author, --trailer and --signoff options to a commit message.
+func addTrailerOptions(message string, options map[string]string, repo *git.Repository) (string, error) {
+	var trailers []metro.Trailer
+	for _, person := range optionValues(options, "co-author") {
+		trailer, err := metro.CoAuthorTrailer(person)
+		if err != nil {
+			return "", err
+		}
+		trailers = append(trailers, trailer)
+	}
+	for _, keyValue := range optionValues(options, "trailer") {
+		trailer, err := metro.ParseTrailer(keyValue)
+		if err != nil {
+			return "", err
+		}
+		trailers = append(trailers, trailer)
+	}
+	if _, signoff := options["signoff"]; signoff {
+		trailer, err := metro.SignoffTrailer(repo)
+		if err != nil {
+			return "", err
+		}
+		trailers = append(trailers, trailer)
+	}
+
+	return metro.AddTrailers(message, trailers), nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+func execTrash(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) < 1 || (positionals[0] != "list" && positionals[0] != "empty") {
+		return errors.New("Incorrect paramater.")
+	}
+	if len(positionals) > 1 {
+		return errors.New("Unexpected argument: " + positionals[1])
+	}
+
+	if positionals[0] == "list" {
+		err := metro.ExpireTrash(repo)
+		if err != nil {
+			return err
+		}
+		lines, err := metro.TrashedLines(repo)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			fmt.Println("The trash is empty.")
+		}
+		for _, line := range lines {
+			fmt.Println(line.Name + " (deleted " + line.Deleted.Format("2006-01-02 15:04") + ")")
+		}
+		return nil
+	}
+
+	count, err := metro.EmptyTrash(repo)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Permanently deleted %d lines.\n", count)
+	return nil
+}
+
+func printTrashHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro trash <list/empty>")
+}
+
+var Trash = Command{"trash", "List or empty deleted lines", journaled("trash", execTrash), printTrashHelp}
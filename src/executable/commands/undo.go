@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+func execUndo(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) > 0 {
+		return errors.New("Unexpected argument: " + positionals[0])
+	}
+
+	op, err := metro.Undo(repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Undid " + op.Name + ".")
+	return nil
+}
+
+func execRedo(repo *git.Repository, positionals []string, _ map[string]string) error {
+	if len(positionals) > 0 {
+		return errors.New("Unexpected argument: " + positionals[0])
+	}
+
+	op, err := metro.Redo(repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Redid " + op.Name + ".")
+	return nil
+}
+
+func printUndoHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro undo")
+}
+
+func printRedoHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro redo")
+}
+
+var Undo = Command{"undo", "Undo the last operation", execUndo, printUndoHelp}
+var Redo = Command{"redo", "Redo the last undone operation", execRedo, printRedoHelp}
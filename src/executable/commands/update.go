@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"metro"
+)
+
+func execUpdate(repo *git.Repository, positionals []string, options map[string]string) error {
+	if len(positionals) > 0 {
+		return errors.New("Unexpected argument: " + positionals[0])
+	}
+	current, err := metro.CurrentBranchName(repo)
+	if err != nil {
+		return err
+	}
+	if parent, ok := options["from"]; ok {
+		parent, err = metro.SetParentLine(current, parent, repo)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Line " + current + " now grows from " + parent + ".")
+	}
+
+	_, replay := options["replay"]
+	stopped, err := metro.Update(replay, repo)
+	if err != nil {
+		return err
+	}
+	if stopped != "" {
+		fmt.Println("Conflicts occurred updating " + stopped + ", please resolve.")
+		if stopped != current {
+			fmt.Println("Then switch back to " + current + " and run metro update again to finish.")
+		}
+	} else {
+		fmt.Println("Updated " + current + ".")
+	}
+	return nil
+}
+
+func printUpdateHelp(_ []string, _ map[string]string) {
+	fmt.Println("Usage: metro update [--replay] [--from <line>]")
+	fmt.Println("Brings the line up to date with the line it grew from, absorbing it unless --replay is given.")
+	fmt.Println("--from sets the line it grows from.")
+}
+
+var Update = Command{"update", "Bring the line up to date with its parent line", journaled("update", execUpdate), printUpdateHelp}
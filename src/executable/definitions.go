@@ -14,9 +14,55 @@ var allCommands = []commands.Command{
 	commands.Patch,
 	commands.Absorb,
 	commands.Resolve,
+	commands.Ignore,
+	commands.History,
+	commands.Show,
+	commands.Undo,
+	commands.Redo,
+	commands.Restore,
+	commands.Trash,
+	commands.Squash,
+	commands.Split,
+	commands.Rewrite,
+	commands.Move,
+	commands.Copy,
+	commands.Revert,
+	commands.Rename,
+	commands.Update,
+	commands.Describe,
+	commands.Lines,
+	commands.Mark,
 }
 
 // List of option tags
 var allOptions = []commands.Option{
-	{"help", "h", false},
+	{"help", "h", false, false},
+	{"untrack", "u", false, false},
+	{"allow-empty", "e", false, false},
+	{"co-author", "c", true, true},
+	{"trailer", "t", true, true},
+	{"signoff", "s", false, false},
+	{"commit", "C", true, false},
+	{"discard", "d", false, false},
+	{"preview", "p", false, false},
+	{"force", "f", false, false},
+	{"since", "S", true, false},
+	{"continue", "n", false, false},
+	{"abort", "a", false, false},
+	{"plan", "P", true, false},
+	{"to", "T", true, false},
+	{"mainline", "m", true, false},
+	{"from", "F", true, false},
+	{"switch", "w", false, false},
+	{"replay", "r", false, false},
+	{"line", "l", true, false},
+	{"owner", "o", true, false},
+	{"issue", "i", true, true},
+	{"status", "x", true, false},
+	{"prune", "N", false, false},
+	{"stale", "D", true, false},
+	{"archive", "A", false, false},
+	{"confirm", "y", false, false},
+	{"at", "b", true, false},
+	{"sign", "g", false, false},
 }
@@ -3,13 +3,20 @@ package metro
 import (
 	"errors"
 	git "github.com/libgit2/git2go"
+	"sort"
 	"strings"
 )
 
 // Create a new branch from the current head with the specified name.
 // Returns the branch
 func CreateBranch(name string, repo *git.Repository) (*git.Branch, error) {
-	commit, err := GetCommit("HEAD", repo)
+	return CreateBranchFrom(name, "HEAD", repo)
+}
+
+// Create a new branch with the specified name, starting from the given commit or line.
+// Returns the branch
+func CreateBranchFrom(name string, revision string, repo *git.Repository) (*git.Branch, error) {
+	commit, err := GetCommit(revision, repo)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +26,23 @@ func CreateBranch(name string, repo *git.Repository) (*git.Branch, error) {
 		return nil, err
 	}
 
+	// Remember which line this one grew from, so it can be updated from it later.
+	if !strings.HasSuffix(name, WipString) {
+		parent := revision
+		if revision == "HEAD" {
+			parent, err = CurrentBranchName(repo)
+			if err != nil {
+				return branch, nil
+			}
+		}
+		if LineExists(parent, repo) && !strings.HasSuffix(parent, WipString) {
+			_, err = SetParentLine(name, parent, repo)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return branch, nil
 }
 
@@ -26,14 +50,34 @@ func SwitchBranch(name string, repo *git.Repository) error {
 	if strings.HasSuffix(name, WipString) {
 		return errors.New("Can't switch to wip line.")
 	}
-	if !CommitExists(name, repo) {
-		return errors.New("No branch called " + name + ".")
+	line, err := ResolveLine(name, repo)
+	if err != nil {
+		return err
+	}
+	if line == "" {
+		return noLineError("No branch called "+name+".", name, repo)
+	}
+	name = line
+	// Unlike an absorb, a replay can't be saved in a WIP commit.
+	if ReplayOngoing(repo) {
+		return errors.New("Branch has conflicts, please finish resolving them before switching.\nRun metro resolve when you are done.")
 	}
 
-	err := SaveWIP(repo)
+	head, err := GetHead(repo)
 	if err != nil {
 		return err
 	}
+	if head.State == HeadViewing || head.State == HeadElsewhere {
+		kept, err := leaveView(name, repo)
+		if err != nil || kept {
+			return err
+		}
+	} else {
+		err = SaveWIP(repo)
+		if err != nil {
+			return err
+		}
+	}
 	err = checkoutBranch(name, repo)
 	if err != nil {
 		return err
@@ -46,6 +90,42 @@ func SwitchBranch(name string, repo *git.Repository) error {
 	return nil
 }
 
+// Prepares to switch from viewing a commit to a line.
+// Changes made while viewing are only kept if the line is at the commit being viewed,
+// in which case the head is moved to the line straight away and true is returned.
+func leaveView(name string, repo *git.Repository) (bool, error) {
+	if assertNoChanges(repo) == nil {
+		return false, nil
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	line, err := GetCommit(name, repo)
+	if err != nil {
+		return false, err
+	}
+	if !line.Id().Equal(head.Id()) || CommitExists(name+WipString, repo) {
+		return false, errors.New("You have changes while viewing a commit.\nKeep them on a new line with metro line <name> --switch.")
+	}
+	return true, moveHead(name, repo)
+}
+
+// Returns true if there is a line with the given name.
+func LineExists(name string, repo *git.Repository) bool {
+	_, err := repo.LookupBranch(name, git.BranchLocal)
+	return err == nil
+}
+
+// Returns the line new repos start on, which other lines usually grow from.
+func trunkLine(repo *git.Repository) (string, error) {
+	name, err := configString("init.defaultBranch", repo)
+	if err != nil || name != "" {
+		return name, err
+	}
+	return "master", nil
+}
+
 // Checks out the given branch by name
 // name - Plain Text branch name (e.g. 'master')
 // repo - Repo to checkout from
@@ -71,7 +151,74 @@ func moveHead(name string, repo *git.Repository) error {
 	return err
 }
 
+// Renames a line along with its WIP. Protected lines can't be renamed, as that would leave the name unprotected.
+// Git moves the line's config, such as its upstream and parent line, to the new name.
+// Its metadata, and the parent of lines that grew from it, are updated to the new name.
+func RenameBranch(oldName string, newName string, repo *git.Repository) error {
+	if strings.HasSuffix(oldName, WipString) {
+		return errors.New("Can't rename a wip line, rename its line instead.")
+	}
+	if strings.HasSuffix(newName, WipString) {
+		return errors.New("Line name can't end in " + WipString)
+	}
+	branch, err := repo.LookupBranch(oldName, git.BranchLocal)
+	if err != nil {
+		return errors.New("No line called " + oldName + ".")
+	}
+	protected, err := IsProtected(oldName, repo)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return errors.New("Line " + oldName + " is protected, it can't be renamed.\nRemove it from " + protectedLinesKey + " first to rename it.")
+	}
+	if LineExists(newName, repo) || LineExists(newName+WipString, repo) {
+		return errors.New("There is already a line called " + newName + ".")
+	}
+	if !git.ReferenceIsValidName("refs/heads/"+newName) || !git.ReferenceIsValidName("refs/heads/"+newName+WipString) {
+		return errors.New("Invalid line name: " + newName)
+	}
+
+	// The line and its WIP are renamed together, or not at all.
+	_, err = branch.Move(newName, false)
+	if err != nil {
+		return err
+	}
+	if LineExists(oldName+WipString, repo) {
+		wip, err := repo.LookupBranch(oldName+WipString, git.BranchLocal)
+		if err == nil {
+			_, err = wip.Move(newName+WipString, false)
+		}
+		if err != nil {
+			if moved, lookupErr := repo.LookupBranch(newName, git.BranchLocal); lookupErr == nil {
+				moved.Move(oldName, false)
+			}
+			return err
+		}
+	}
+
+	err = renameParentLine(oldName, newName, repo)
+	if err != nil {
+		return err
+	}
+	return renameLineMetadata(oldName, newName, repo)
+}
+
+// Moves a line and its WIP to the trash, from where they can be restored until they expire.
 func DeleteBranch(name string, repo *git.Repository) error {
+	err := assertUnprotected(name, repo)
+	if err != nil {
+		return err
+	}
+	err = trashBranch(name, repo)
+	if err != nil {
+		return err
+	}
+	return ExpireTrash(repo)
+}
+
+// Deletes a branch immediately, without moving it to the trash.
+func deleteBranch(name string, repo *git.Repository) error {
 	branch, err := repo.LookupBranch(name, git.BranchLocal)
 	if err != nil {
 		return err
@@ -84,19 +231,38 @@ func DeleteBranch(name string, repo *git.Repository) error {
 	return nil
 }
 
-func CurrentBranchName(repo *git.Repository) (string, error) {
+// Returns the names of all lines in the repo, in alphabetical order, leaving out their WIPs.
+func Lines(repo *git.Repository) ([]string, error) {
 	iterator, err := repo.NewBranchIterator(git.BranchLocal)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	var names []string
 	for branch, _, err := iterator.Next(); err == nil; branch, _, err = iterator.Next() {
-		head, err := branch.IsHead()
+		name, err := branch.Name()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		if head {
-			return branch.Name()
+		if !strings.HasSuffix(name, WipString) {
+			names = append(names, name)
 		}
 	}
-	return "", errors.New("Could not find current line.")
+	sort.Strings(names)
+	return names, nil
+}
+
+// Returns the name of the line HEAD is on, or the name of the WIP line if HEAD was left on one.
+// Returns an error explaining how to get back to a line if HEAD isn't on one.
+func CurrentBranchName(repo *git.Repository) (string, error) {
+	head, err := GetHead(repo)
+	if err != nil {
+		return "", err
+	}
+	switch head.State {
+	case HeadOnLine, HeadUnborn:
+		return head.Line, nil
+	case HeadOnWip:
+		return head.Line + WipString, nil
+	}
+	return "", head.notOnLineError()
 }
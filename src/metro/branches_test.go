@@ -0,0 +1,113 @@
+package metro
+
+import (
+	"testing"
+)
+
+func TestRenameBranchWithWip(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave work in progress on feature.
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, repo, map[string]string{"f.txt": "unfinished\n"})
+	err = SwitchBranch(main, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("other", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetLineMetadata("feature", LineMetadata{Description: "A feature"}, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Refused renames leave the line and its WIP together under the old name.
+	for _, name := range []string{"other", "feature" + WipString, "bad..name"} {
+		if err := RenameBranch("feature", name, repo); err == nil {
+			t.Errorf("RenameBranch to %q succeeded, want error", name)
+		}
+		if !LineExists("feature", repo) || !LineExists("feature"+WipString, repo) {
+			t.Fatalf("Refused RenameBranch to %q moved the line or its WIP", name)
+		}
+	}
+
+	err = RenameBranch("feature", "renamed", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if LineExists("feature", repo) || LineExists("feature"+WipString, repo) {
+		t.Error("RenameBranch left the line or its WIP under the old name")
+	}
+	if !LineExists("renamed", repo) || !LineExists("renamed"+WipString, repo) {
+		t.Error("RenameBranch didn't move both the line and its WIP")
+	}
+	metadata, err := GetLineMetadata("renamed", repo)
+	if err != nil || metadata.Description != "A feature" {
+		t.Errorf("Metadata after RenameBranch = %+v, %v, want it moved to the new name", metadata, err)
+	}
+}
+
+func TestCreateBranchFrom(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+
+	// A line started from a commit begins there and has no parent line.
+	_, err = CreateBranchFrom("fix", ShortID(first), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fix, err := GetCommit("fix", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fix.Id().Equal(first.Id()) {
+		t.Error("CreateBranchFrom a commit didn't start the line at it")
+	}
+	if parent, err := ParentLine("fix", repo); err != nil || parent != "" {
+		t.Errorf("ParentLine of a line from a commit = %q, %v, want none", parent, err)
+	}
+
+	// A line started from another line begins at its tip and remembers it.
+	commitTestFiles(t, repo, "Add c", map[string]string{"c.txt": "c\n"})
+	_, err = CreateBranchFrom("feature", "fix", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feature, err := GetCommit("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feature.Id().Equal(first.Id()) {
+		t.Error("CreateBranchFrom a line didn't start at the line's tip")
+	}
+	if parent, err := ParentLine("feature", repo); err != nil || parent != "fix" {
+		t.Errorf("ParentLine of a line from fix = %q, %v, want fix", parent, err)
+	}
+	if parent, err := ParentLine(main, repo); err != nil || parent != "" {
+		t.Errorf("ParentLine of %s = %q, %v, want none", main, parent, err)
+	}
+
+	if _, err := CreateBranchFrom("other", "nothing", repo); err == nil {
+		t.Error("CreateBranchFrom an unknown revision succeeded, want error")
+	}
+}
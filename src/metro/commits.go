@@ -4,7 +4,6 @@ import (
 	"errors"
 	git "github.com/libgit2/git2go"
 	"strings"
-	"time"
 )
 
 // Commit all files in the repo directory (excluding those in .gitignore) to the head of the current branch.
@@ -12,55 +11,153 @@ import (
 // message: The commit message
 // parentRevs: The revisions corresponding to the commit's parents
 func Commit(repo *git.Repository, message string, parentRevs ...string) error {
-	// The commit author.
-	// TODO: Use an actual user signature
-	author := git.Signature{
-		"Test User",
-		"test@email.com",
-		time.Now(),
+	tree, err := stageAll(repo)
+	if err != nil {
+		return err
+	}
+
+	// Retrieve the commit objects associated with the given parent revisions.
+	var parentCommits []*git.Commit
+	for _, parentRev := range parentRevs {
+		parentCommit, err := GetCommit(parentRev, repo)
+		if err != nil {
+			return err
+		}
+
+		parentCommits = append(parentCommits, parentCommit)
+	}
+
+	return commitTree(repo, message, tree, parentCommits...)
+}
+
+// Commit all changes in the working directory to the head of the current branch, on top of the current head.
+// Unless allowEmpty is true, refuses to commit if nothing has changed since the head commit.
+// Returns the files changed by the new commit.
+func CommitChanges(repo *git.Repository, message string, allowEmpty bool) ([]FileChange, error) {
+	err := assertOnLine(repo)
+	if err != nil {
+		return nil, err
+	}
+	err = assertCanCommit(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := stageAll(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first commit of a line that has none yet has no parent.
+	state, err := GetHead(repo)
+	if err != nil {
+		return nil, err
+	}
+	if state.State == HeadUnborn {
+		err = commitTree(repo, message, tree)
+		if err != nil {
+			return nil, err
+		}
+		return diffTrees(nil, tree, repo)
+	}
+
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return nil, err
+	}
+	if !allowEmpty && tree.Id().Equal(head.TreeId()) {
+		return nil, errors.New("Nothing to commit, no files have changed since the last commit.")
+	}
+
+	err = commitTree(repo, message, tree, head)
+	if err != nil {
+		return nil, err
 	}
 
+	return ChangedFiles(head.Id().String(), "HEAD", repo)
+}
+
+// Raises an error if the working directory has changes that haven't been committed.
+func assertNoChanges(repo *git.Repository) error {
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return err
+	}
+	tree, err := workingTree(repo)
+	if err != nil {
+		return err
+	}
+	if !tree.Id().Equal(head.TreeId()) {
+		return errors.New("You have changes that haven't been committed.\nCommit them with metro commit, or add them to the last commit with metro patch.")
+	}
+	return nil
+}
+
+// Stage all files in the repo directory (excluding those in .gitignore) and write them to a tree.
+func stageAll(repo *git.Repository) (*git.Tree, error) {
 	// Get the repo's index, which we will use to the stage the files to be committed.
 	index, err := repo.Index()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = index.AddAll(nil, git.IndexAddDisablePathspecMatch, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Write the files in the index into a tree that can be attached to the commit.
 	oid, err := index.WriteTree()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tree, err := repo.LookupTree(oid)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Save the index to disk so that it stays in sync with the contents of the working directory.
 	// If we don't do this removals of every file are left staged.
 	err = index.Write()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Retrieve the commit objects associated with the given parent revisions.
-	var parentCommits []*git.Commit
-	for _, parentRev := range parentRevs {
-		parentCommit, err := GetCommit(parentRev, repo)
-		if err != nil {
-			return err
-		}
+	return tree, nil
+}
 
-		parentCommits = append(parentCommits, parentCommit)
+// Write all files in the repo directory (excluding those in .gitignore) to a tree, as stageAll does,
+// without staging them. The files are added to a separate copy of the index, which is thrown away.
+func workingTree(repo *git.Repository) (*git.Tree, error) {
+	scratch, err := git.OpenRepository(repo.Workdir())
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.Free()
+	index, err := scratch.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	err = index.AddAll(nil, git.IndexAddDisablePathspecMatch, nil)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := index.WriteTree()
+	if err != nil {
+		return nil, err
+	}
+	return repo.LookupTree(oid)
+}
+
+// Commit the given tree to the head of the current branch.
+func commitTree(repo *git.Repository, message string, tree *git.Tree, parents ...*git.Commit) error {
+	author, err := userSignature(repo)
+	if err != nil {
+		return err
 	}
 
 	// Commit the files to the head of the current branch.
-	_, err = repo.CreateCommit("HEAD", &author, &author, message, tree, parentCommits...)
+	_, err = repo.CreateCommit("HEAD", author, author, message, tree, parents...)
 	if err != nil {
 		return err
 	}
@@ -68,42 +165,205 @@ func Commit(repo *git.Repository, message string, parentRevs ...string) error {
 	return nil
 }
 
+// The signature of the current user, taken from the user.name and user.email config values.
+// Raises an error if they aren't set.
+func userSignature(repo *git.Repository) (*git.Signature, error) {
+	signature, err := repo.DefaultSignature()
+	if err != nil {
+		return nil, errors.New("Please tell Metro who you are by running:\n" +
+			"git config --global user.name \"Your Name\"\n" +
+			"git config --global user.email \"you@example.com\"")
+	}
+	return signature, nil
+}
+
 // Gets the commit corresponding to the given revision
+// The start of a line's name is accepted too, if only one line starts with it.
 // revision - Revision of the commit to find
 // repo - Repo to find the commit in
 //
 // Returns the commit
 func GetCommit(revision string, repo *git.Repository) (*git.Commit, error) {
-	obj, err := repo.RevparseSingle(revision)
-	if err != nil {
+	commit, err := lookupRevision(revision, repo)
+	if err == nil {
+		return commit, nil
+	}
+
+	if revision == "HEAD" {
+		head, headErr := GetHead(repo)
+		if headErr == nil && head.State == HeadUnborn {
+			return nil, errors.New("Line " + head.Line + " has no commits yet.\nMake the first commit with metro commit.")
+		}
 		return nil, err
 	}
-	commit, err := obj.AsCommit()
+	// Malformed or ambiguous revisions keep their own error.
+	if !git.IsErrorCode(err, git.ErrNotFound) {
+		return nil, err
+	}
+
+	// Fall back to lines starting with the revision, so long line names can be shortened.
+	line, lineErr := ResolveLine(revision, repo)
+	if lineErr != nil {
+		return nil, lineErr
+	}
+	if line != "" {
+		return lookupRevision(line, repo)
+	}
+	return nil, noLineError("No line or commit called "+revision+".", revision, repo)
+}
+
+// Finds the commit a revision refers to exactly, without matching the start of line names.
+func lookupRevision(revision string, repo *git.Repository) (*git.Commit, error) {
+	obj, err := repo.RevparseSingle(revision)
 	if err != nil {
 		return nil, err
 	}
-	return commit, nil
+	// Marks point to a tag object rather than the commit itself.
+	if obj.Type() == git.ObjectTag {
+		obj, err = obj.Peel(git.ObjectCommit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return obj.AsCommit()
 }
 
-// TODO: Make this work with commits with more than one parent
+// Replaces the head commit with one containing the current work and the given message.
+// The new commit has the same parents as the old one, so patching an absorb commit keeps it an absorb.
 func Patch(repo *git.Repository, message string) error {
 	err := AssertMerging(repo)
 	if err != nil {
 		return err
 	}
+	err = assertOnLine(repo)
+	if err != nil {
+		return err
+	}
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return err
+	}
+
+	// Remember the parents before the head commit is deleted.
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return err
+	}
+	var parentRevs []string
+	for i := uint(0); i < head.ParentCount(); i++ {
+		parentRevs = append(parentRevs, head.ParentId(i).String())
+	}
 
 	err = DeleteLastCommit(repo, false)
 	if err != nil {
 		return err
 	}
 
-	err = Commit(repo, message, "HEAD^{commit}")
+	err = Commit(repo, message, parentRevs...)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Adds the current work to an earlier commit on the current line and gives it the given message,
+// then replays the commits after it on top of the patched commit.
+// If replaying a commit causes conflicts the replay stops and true is returned,
+// and the rest of the commits are replayed by ContinueReplay once the conflicts are resolved.
+func PatchCommit(repo *git.Repository, revision string, message string) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertOnLine(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := GetCommit(revision, repo)
+	if err != nil {
+		return false, err
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	if target.Id().Equal(head.Id()) {
+		return false, Patch(repo, message)
+	}
+	later, err := commitsAfter(target, repo)
+	if err != nil {
+		return false, err
+	}
+
+	// Apply the changes made since the head commit to the target commit.
+	workTree, err := stageAll(repo)
+	if err != nil {
+		return false, err
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return false, err
+	}
+	patchedTree := targetTree
+	if !workTree.Id().Equal(head.TreeId()) {
+		headTree, err := head.Tree()
+		if err != nil {
+			return false, err
+		}
+		mergeOptions, err := git.DefaultMergeOptions()
+		if err != nil {
+			return false, err
+		}
+		index, err := repo.MergeTrees(headTree, targetTree, workTree, &mergeOptions)
+		if err != nil {
+			return false, err
+		}
+		if index.HasConflicts() {
+			return false, errors.New("Your changes conflict with commit " + ShortID(target) + ", so it can't be patched.")
+		}
+		oid, err := index.WriteTreeTo(repo)
+		if err != nil {
+			return false, err
+		}
+		patchedTree, err = repo.LookupTree(oid)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var parents []*git.Commit
+	for i := uint(0); i < target.ParentCount(); i++ {
+		parents = append(parents, target.Parent(i))
+	}
+	committer, err := userSignature(repo)
+	if err != nil {
+		return false, err
+	}
+	patchedID, err := repo.CreateCommit("", target.Author(), committer, message, patchedTree, parents...)
+	if err != nil {
+		return false, err
+	}
+	patched, err := repo.LookupCommit(patchedID)
+	if err != nil {
+		return false, err
+	}
+
+	// The work is now part of the patched commit, so the working directory can be reset to it.
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	err = repo.ResetToCommit(patched, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return false, err
+	}
+
+	return replay("patch", head.Id(), workTree.Id(), pickSteps(later), repo)
+}
+
 // Reverts the last commit WITHOUT leaving a trace of the reverted commit
 // reset - If true, commit is deleted and working directory reset to last commit
 //		   Otherwise working directory is unchanged
@@ -119,6 +379,10 @@ func DeleteCommits(repo *git.Repository, commitsBack int, reset bool) error {
 	if commitsBack < 1 {
 		return errors.New("Invalid commit to delete.")
 	}
+	err := assertCanRewrite(repo)
+	if err != nil {
+		return err
+	}
 
 	// Gets head commit
 	commit, err := GetCommit("HEAD", repo)
@@ -153,6 +417,76 @@ func DeleteCommits(repo *git.Repository, commitsBack int, reset bool) error {
 	return err
 }
 
+// Returns the commits that would be removed from the current line by deleting the given number of commits,
+// newest first.
+func CommitsToDelete(repo *git.Repository, commitsBack int) ([]*git.Commit, error) {
+	if commitsBack < 1 {
+		return nil, errors.New("Invalid commit to delete.")
+	}
+
+	// One more commit is needed for the head to be moved back to.
+	commits, err := LineHistory("HEAD", commitsBack+1, repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) <= commitsBack {
+		return nil, errors.New("Can't delete the first commit of the repo.")
+	}
+	return commits[:commitsBack], nil
+}
+
+// Raises an error if any of the given commits is an absorb commit or has already been synced,
+// since deleting them would lose the record of the absorb or rewrite history others have.
+func AssertSafeToDelete(commits []*git.Commit, repo *git.Repository) error {
+	for _, commit := range commits {
+		if commit.ParentCount() > 1 {
+			return errors.New("Commit " + ShortID(commit) + " is an absorb, deleting it would lose the absorbed changes.")
+		}
+		synced, err := isSynced(commit, repo)
+		if err != nil {
+			return err
+		}
+		if synced {
+			return errors.New("Commit " + ShortID(commit) + " has already been synced, deleting it would rewrite history others have.")
+		}
+	}
+	return nil
+}
+
+// Returns true if the commit is part of any line fetched from or pushed to a remote.
+func isSynced(commit *git.Commit, repo *git.Repository) (bool, error) {
+	iterator, err := repo.NewBranchIterator(git.BranchRemote)
+	if err != nil {
+		return false, err
+	}
+	defer iterator.Free()
+
+	for {
+		branch, _, err := iterator.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// Remote HEAD refs are symbolic and have no target of their own.
+		tip := branch.Target()
+		if tip == nil {
+			continue
+		}
+		if tip.Equal(commit.Id()) {
+			return true, nil
+		}
+		contains, err := repo.DescendantOf(tip, commit.Id())
+		if err != nil {
+			return false, err
+		}
+		if contains {
+			return true, nil
+		}
+	}
+}
+
 // Checks out the given commit without moving head,
 // such that the working directory will match the commit contents.
 // Doesn't change current branch ref.
@@ -177,7 +511,7 @@ func checkout(name string, repo *git.Repository) error {
 }
 
 func CommitExists(name string, repo *git.Repository) bool {
-	_, err := GetCommit(name, repo)
+	_, err := lookupRevision(name, repo)
 	return err == nil
 }
 
@@ -211,7 +545,7 @@ func SaveWIP(repo *git.Repository) error {
 
 	// If WIP already exists, delete
 	if CommitExists(name+WipString, repo) {
-		err = DeleteBranch(name+WipString, repo)
+		err = deleteBranch(name+WipString, repo)
 		if err != nil {
 			return err
 		}
@@ -228,7 +562,7 @@ func SaveWIP(repo *git.Repository) error {
 
 	merging := MergeOngoing(repo)
 	if merging {
-		message, err := getMergeMessage(repo)
+		message, err := MergeMessage(repo)
 		if err != nil {
 			return err
 		}
@@ -312,7 +646,7 @@ func RestoreWIP(repo *git.Repository) error {
 		return err
 	}
 
-	err = DeleteBranch(name+WipString, repo)
+	err = deleteBranch(name+WipString, repo)
 	if err != nil {
 		return err
 	}
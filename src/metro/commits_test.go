@@ -0,0 +1,234 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	commitTestFiles(t, repo, "Add a and b", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+
+	writeTestFiles(t, repo, map[string]string{"a.txt": "changed\n", "c.txt": "c\n"})
+	err := os.Remove(filepath.Join(repo.Workdir(), "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := CommitChanges(repo, "Change files", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{{"a.txt", git.DeltaModified}, {"b.txt", git.DeltaDeleted}, {"c.txt", git.DeltaAdded}}
+	if len(changes) != len(want) {
+		t.Fatalf("CommitChanges = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("CommitChanges = %+v, want %+v", changes, want)
+			break
+		}
+	}
+}
+
+func TestCommitChangesRefusesEmptyCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	head := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+
+	_, err := CommitChanges(repo, "Nothing", false)
+	if err == nil || !strings.Contains(err.Error(), "Nothing to commit") {
+		t.Errorf("CommitChanges with no changes = %v, want error", err)
+	}
+	current, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Id().Equal(head.Id()) {
+		t.Fatal("A refused empty commit was made anyway")
+	}
+
+	changes, err := CommitChanges(repo, "Nothing, on purpose", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("An empty commit changed %+v", changes)
+	}
+	current, err = GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Summary() != "Nothing, on purpose" || !current.TreeId().Equal(head.TreeId()) {
+		t.Error("CommitChanges with allowEmpty didn't make the empty commit")
+	}
+}
+
+// Make an absorb of a new line into the current one, returning it.
+func absorbTestLine(t *testing.T, repo *git.Repository, line string) *git.Commit {
+	current, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch(line, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch(line, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on "+line, map[string]string{line + ".txt": line + "\n"})
+	err = SwitchBranch(current, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on "+current, map[string]string{current + ".txt": current + "\n"})
+	conflicts, err := Absorb(line, repo)
+	if err != nil || conflicts {
+		t.Fatalf("Absorb(%s) = %v, %v", line, conflicts, err)
+	}
+	absorb, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return absorb
+}
+
+func TestPatchKeepsAbsorbParents(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	absorb := absorbTestLine(t, repo, "feature")
+
+	writeTestFiles(t, repo, map[string]string{"feature.txt": "patched\n"})
+	err := Patch(repo, absorb.Message())
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.ParentCount() != 2 || !patched.ParentId(0).Equal(absorb.ParentId(0)) || !patched.ParentId(1).Equal(absorb.ParentId(1)) {
+		t.Error("Patching an absorb didn't keep both of its parents")
+	}
+	if patched.Message() != absorb.Message() {
+		t.Errorf("Patched absorb has message %q, want %q", patched.Message(), absorb.Message())
+	}
+	if got := testFileAt(t, patched, "feature.txt", repo); got != "patched\n" {
+		t.Errorf("Patched absorb has feature.txt %q, want the patch", got)
+	}
+}
+
+func TestPatchCommitReplaysLaterCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	target := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": numberedLines(10, nil)})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+	commitTestFiles(t, repo, "Change a", map[string]string{"a.txt": numberedLines(10, map[int]string{9: "nine"})})
+
+	// Fix a typo in the first commit, far enough from the later change not to conflict with it.
+	writeTestFiles(t, repo, map[string]string{"a.txt": numberedLines(10, map[int]string{1: "one", 9: "nine"})})
+	conflicts, err := PatchCommit(repo, ShortID(target), "Add a, fixed")
+	if err != nil || conflicts {
+		t.Fatalf("PatchCommit = %v, %v", conflicts, err)
+	}
+	if ReplayOngoing(repo) {
+		t.Error("PatchCommit without conflicts left a replay ongoing")
+	}
+
+	commits, err := LineHistory("HEAD", 4, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries := []string{"Change a", "Add b", "Add a, fixed", "Create repository"}
+	for i, summary := range summaries {
+		if commits[i].Summary() != summary {
+			t.Errorf("Commit %d is %q, want %q", i, commits[i].Summary(), summary)
+		}
+	}
+	if got := testFileAt(t, commits[2], "a.txt", repo); got != numberedLines(10, map[int]string{1: "one"}) {
+		t.Errorf("Patched commit has a.txt %q", got)
+	}
+	if got := testFileAt(t, commits[0], "a.txt", repo); got != numberedLines(10, map[int]string{1: "one", 9: "nine"}) {
+		t.Errorf("Replayed head has a.txt %q", got)
+	}
+	if commits[2].Author().When.Unix() != target.Author().When.Unix() {
+		t.Error("PatchCommit changed the patched commit's author")
+	}
+}
+
+func TestDeleteCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	base := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+	commitTestFiles(t, repo, "Add c", map[string]string{"c.txt": "c\n"})
+
+	commits, err := CommitsToDelete(repo, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Summary() != "Add c" || commits[1].Summary() != "Add b" {
+		t.Fatalf("CommitsToDelete = %v, want Add c and Add b", commits)
+	}
+	if _, err := CommitsToDelete(repo, 4); err == nil {
+		t.Error("CommitsToDelete past the first commit succeeded, want error")
+	}
+
+	// Without discarding, the changes stay in the working directory.
+	err = DeleteCommits(repo, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !head.Id().Equal(base.Id()) {
+		t.Fatal("DeleteCommits didn't move the line back")
+	}
+	if err := assertNoChanges(repo); err == nil {
+		t.Error("DeleteCommits lost the deleted commits' changes")
+	}
+
+	commitTestFiles(t, repo, "Add b and c", nil)
+	err = DeleteCommits(repo, 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := assertNoChanges(repo); err != nil {
+		t.Errorf("DeleteCommits discarding changes left %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Workdir(), "c.txt")); !os.IsNotExist(err) {
+		t.Error("DeleteCommits discarding changes left c.txt")
+	}
+}
+
+func TestAssertSafeToDelete(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	absorb := absorbTestLine(t, repo, "feature")
+	plain := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+
+	if err := AssertSafeToDelete([]*git.Commit{plain}, repo); err != nil {
+		t.Errorf("AssertSafeToDelete of a local commit = %v", err)
+	}
+	err := AssertSafeToDelete([]*git.Commit{plain, absorb}, repo)
+	if err == nil || !strings.Contains(err.Error(), "absorb") {
+		t.Errorf("AssertSafeToDelete of an absorb = %v, want error", err)
+	}
+
+	// A commit on a line fetched from a remote has been synced.
+	_, err = repo.References.Create("refs/remotes/origin/main", plain.Id(), false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AssertSafeToDelete([]*git.Commit{plain}, repo)
+	if err == nil || !strings.Contains(err.Error(), "synced") {
+		t.Errorf("AssertSafeToDelete of a synced commit = %v, want error", err)
+	}
+}
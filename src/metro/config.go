@@ -0,0 +1,53 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+)
+
+// Look up a string in the repo's config, returning "" if it isn't set.
+func configString(key string, repo *git.Repository) (string, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	value, err := config.LookupString(key)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return "", nil
+	}
+	return value, err
+}
+
+// Look up an integer in the repo's config, returning def if it isn't set.
+func configInt(key string, def int, repo *git.Repository) (int, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return 0, err
+	}
+	value, err := config.LookupInt32(key)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return def, nil
+	}
+	return int(value), err
+}
+
+// Look up a boolean in the repo's config, returning false if it isn't set.
+func configBool(key string, repo *git.Repository) (bool, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return false, err
+	}
+	value, err := config.LookupBool(key)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return false, nil
+	}
+	return value, err
+}
+
+// Set a string in the repo's config.
+func setConfigString(key string, value string, repo *git.Repository) error {
+	config, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	return config.SetString(key, value)
+}
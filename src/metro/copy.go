@@ -0,0 +1,45 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+)
+
+// Recreates commits from any line on top of the current line, oldest first, keeping their authors.
+// Each copy records the commit it was copied from in a trailer.
+// Returns true if a commit stopped with conflicts, ContinueReplay then finishes the copy.
+func CopyCommits(commits []*git.Commit, repo *git.Repository) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertCanCommit(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertNoChanges(repo)
+	if err != nil {
+		return false, err
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+
+	var steps []replayStep
+	for _, commit := range commits {
+		if commit.ParentCount() == 0 {
+			return false, errors.New("Commit " + ShortID(commit) + " is the first commit of its line and can't be copied.")
+		}
+		descendant, err := repo.DescendantOf(head.Id(), commit.Id())
+		if err != nil {
+			return false, err
+		}
+		if descendant || head.Id().Equal(commit.Id()) {
+			return false, errors.New("Commit " + ShortID(commit) + " is already on the current line.")
+		}
+		message := AddTrailers(commit.Message(), []Trailer{{CopiedFromKey, commit.Id().String()}})
+		steps = append(steps, replayStep{Id: commit.Id().String(), Message: message})
+	}
+	return replay("copy", head.Id(), nil, steps, repo)
+}
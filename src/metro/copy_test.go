@@ -0,0 +1,72 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"testing"
+	"time"
+)
+
+func TestCopyCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := commitTestFiles(t, repo, "Add f", map[string]string{"f.txt": "f\n"})
+
+	// The same change made by someone else, a while ago.
+	tree, err := work.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := &git.Signature{Name: "Other Author", Email: "other@email.com", When: time.Unix(1500000000, 0)}
+	oid, err := repo.CreateCommit("", author, author, "Add f\n", tree, work.Parent(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := repo.LookupCommit(oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SwitchBranch(main, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	conflicts, err := CopyCommits([]*git.Commit{original}, repo)
+	if err != nil || conflicts {
+		t.Fatalf("CopyCommits = %v, %v", conflicts, err)
+	}
+
+	copied, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.ParentCount() != 1 || !copied.ParentId(0).Equal(head.Id()) {
+		t.Error("The copy isn't on top of the current line")
+	}
+	if testFileAt(t, copied, "f.txt", repo) != "f\n" || testFileAt(t, copied, "a.txt", repo) != "a\n" {
+		t.Error("The copy doesn't have both the copied changes and the line's own")
+	}
+	if copied.Author().Name != author.Name || copied.Author().Email != author.Email || !copied.Author().When.Equal(author.When) {
+		t.Errorf("The copy's author is %+v, want %+v", copied.Author(), author)
+	}
+	body, trailers := ParseTrailers(copied.Message())
+	if body != "Add f" || len(trailers) != 1 || trailers[0] != (Trailer{CopiedFromKey, original.Id().String()}) {
+		t.Errorf("The copy has message %q, want it to record where it was copied from", copied.Message())
+	}
+
+	if _, err := CopyCommits([]*git.Commit{head}, repo); err == nil {
+		t.Error("CopyCommits of a commit already on the line succeeded, want error")
+	}
+}
@@ -0,0 +1,63 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+)
+
+// A file that differs between two commits.
+type FileChange struct {
+	Path   string
+	Status git.Delta
+}
+
+// Returns the files that changed between two revisions.
+// fromRev - The revision to compare against
+// toRev - The revision containing the changes
+func ChangedFiles(fromRev string, toRev string, repo *git.Repository) ([]FileChange, error) {
+	fromCommit, err := GetCommit(fromRev, repo)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := GetCommit(toRev, repo)
+	if err != nil {
+		return nil, err
+	}
+	fromTree, err := fromCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := toCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return diffTrees(fromTree, toTree, repo)
+}
+
+// Returns the files that changed between two trees.
+func diffTrees(fromTree *git.Tree, toTree *git.Tree, repo *git.Repository) ([]FileChange, error) {
+	diff, err := repo.DiffTreeToTree(fromTree, toTree, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	count, err := diff.NumDeltas()
+	if err != nil {
+		return nil, err
+	}
+	var changes []FileChange
+	for i := 0; i < count; i++ {
+		delta, err := diff.GetDelta(i)
+		if err != nil {
+			return nil, err
+		}
+
+		path := delta.NewFile.Path
+		if delta.Status == git.DeltaDeleted {
+			path = delta.OldFile.Path
+		}
+		changes = append(changes, FileChange{path, delta.Status})
+	}
+	return changes, nil
+}
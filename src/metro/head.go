@@ -0,0 +1,115 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// What HEAD points to.
+type HeadState int
+
+const (
+	// On a line with at least one commit.
+	HeadOnLine HeadState = iota
+	// On a line that has no commits yet, as in a freshly initialised git repo.
+	HeadUnborn
+	// Detached at a commit, viewing it read-only.
+	HeadViewing
+	// On a line's WIP, left behind by a switch that was interrupted or by using git directly.
+	HeadOnWip
+	// On a ref that isn't a line, such as a remote branch, left behind by using git directly.
+	HeadElsewhere
+)
+
+// The state of HEAD.
+type Head struct {
+	State HeadState
+	// The line HEAD is on, or whose WIP it is on. Empty when viewing a commit or elsewhere.
+	Line string
+	// The ref HEAD points to, or "" when viewing a commit.
+	Ref string
+}
+
+// Finds out what HEAD points to.
+func GetHead(repo *git.Repository) (*Head, error) {
+	ref, err := repo.References.Lookup("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if ref.Type() != git.ReferenceSymbolic {
+		return &Head{State: HeadViewing}, nil
+	}
+
+	target := ref.SymbolicTarget()
+	if !strings.HasPrefix(target, "refs/heads/") {
+		return &Head{State: HeadElsewhere, Ref: target}, nil
+	}
+	name := strings.TrimPrefix(target, "refs/heads/")
+	if strings.HasSuffix(name, WipString) {
+		return &Head{State: HeadOnWip, Line: strings.TrimSuffix(name, WipString), Ref: target}, nil
+	}
+	_, err = repo.References.Lookup(target)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return &Head{State: HeadUnborn, Line: name, Ref: target}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Head{State: HeadOnLine, Line: name, Ref: target}, nil
+}
+
+// An error explaining how to get back to a line from a HEAD that isn't on one.
+func (head *Head) notOnLineError() error {
+	switch head.State {
+	case HeadViewing:
+		return errors.New("You are viewing a commit rather than a line.\nUse metro switch <line> to go back to a line, or metro line <name> --switch to start one here.")
+	case HeadElsewhere:
+		return errors.New("HEAD points to " + head.Ref + ", which isn't a line.\nUse metro switch <line> to go back to a line.")
+	}
+	return nil
+}
+
+// Puts HEAD back on a line if it was left on the line's WIP by an interrupted switch or by git.
+// The working directory and index are not changed. The WIP is deleted if its contents are already in the working
+// directory or it was never committed to, otherwise it is kept to be restored when next switching to the line.
+// Returns the line HEAD was moved back to, or "" if HEAD didn't need recovering.
+func RecoverHead(repo *git.Repository) (string, error) {
+	head, err := GetHead(repo)
+	if err != nil {
+		return "", err
+	}
+	if head.State != HeadOnWip {
+		return "", nil
+	}
+	if !LineExists(head.Line, repo) {
+		return "", errors.New("You are on the WIP of " + head.Line + ", but the line no longer exists.\n" +
+			"Use metro restore line " + head.Line + " to bring it back, or metro line <name> --switch to keep the work on a new line.")
+	}
+
+	wip, err := GetCommit(head.Line+WipString, repo)
+	if err != nil {
+		return "", err
+	}
+	line, err := GetCommit(head.Line, repo)
+	if err != nil {
+		return "", err
+	}
+	tree, err := workingTree(repo)
+	if err != nil {
+		return "", err
+	}
+
+	err = moveHead(head.Line, repo)
+	if err != nil {
+		return "", err
+	}
+	// A WIP saved during an absorb also holds the absorb, so it is always kept.
+	if wip.Id().Equal(line.Id()) || (wip.ParentCount() < 2 && wip.TreeId().Equal(tree.Id())) {
+		err = deleteBranch(head.Line+WipString, repo)
+		if err != nil {
+			return "", err
+		}
+	}
+	return head.Line, nil
+}
@@ -0,0 +1,141 @@
+package metro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetHead(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.References.Create("refs/remotes/origin/"+main, commit.Id(), false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch(main+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		move func() error
+		want Head
+	}{
+		{func() error { return repo.SetHead("refs/heads/" + main) }, Head{HeadOnLine, main, "refs/heads/" + main}},
+		{func() error { return repo.SetHead("refs/heads/empty") }, Head{HeadUnborn, "empty", "refs/heads/empty"}},
+		{func() error { return repo.SetHeadDetached(commit.Id()) }, Head{HeadViewing, "", ""}},
+		{func() error { return repo.SetHead("refs/heads/" + main + WipString) }, Head{HeadOnWip, main, "refs/heads/" + main + WipString}},
+		{func() error { return repo.SetHead("refs/remotes/origin/" + main) }, Head{HeadElsewhere, "", "refs/remotes/origin/" + main}},
+	}
+	for _, test := range tests {
+		err := test.move()
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := GetHead(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *head != test.want {
+			t.Errorf("GetHead = %+v, want %+v", *head, test.want)
+		}
+	}
+}
+
+func TestRecoverHead(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := RecoverHead(repo)
+	if err != nil || line != "" {
+		t.Errorf("RecoverHead on a line = %q, %v, want nothing to recover", line, err)
+	}
+
+	// A WIP that was never committed to is dropped.
+	_, err = CreateBranch(main+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = moveHead(main+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err = RecoverHead(repo)
+	if err != nil || line != main {
+		t.Fatalf("RecoverHead = %q, %v, want %s", line, err, main)
+	}
+	head, err := GetHead(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.State != HeadOnLine || head.Line != main {
+		t.Errorf("HEAD is %+v after RecoverHead, want on %s", *head, main)
+	}
+	if LineExists(main+WipString, repo) {
+		t.Error("RecoverHead kept a WIP with no work in it")
+	}
+
+	// A WIP with work that isn't in the working directory is kept to be restored later.
+	writeTestFiles(t, repo, map[string]string{"w.txt": "saved\n"})
+	tree, err := workingTree(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := userSignature(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateCommit("refs/heads/"+main+WipString, signature, signature, "WIP", tree, tip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = moveHead(main+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, repo, map[string]string{"w.txt": "changed since\n"})
+	line, err = RecoverHead(repo)
+	if err != nil || line != main {
+		t.Fatalf("RecoverHead = %q, %v, want %s", line, err, main)
+	}
+	if !LineExists(main+WipString, repo) {
+		t.Error("RecoverHead dropped a WIP with work that isn't in the working directory")
+	}
+	current, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Id().Equal(tip.Id()) {
+		t.Error("RecoverHead didn't put HEAD back at the line's tip")
+	}
+
+	// Without the line there is nothing to go back to.
+	_, err = CreateBranch("gone"+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = moveHead("gone"+WipString, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = RecoverHead(repo)
+	if err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("RecoverHead on the WIP of a missing line = %v, want error", err)
+	}
+}
@@ -0,0 +1,113 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+)
+
+// Returns the commits on the line leading up to the given revision, newest first.
+// Only the first parent of each commit is followed, so commits from absorbed lines are left out.
+// limit - The maximum number of commits to return, or 0 for no limit
+func LineHistory(revision string, limit int, repo *git.Repository) ([]*git.Commit, error) {
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*git.Commit
+	for commit != nil && (limit == 0 || len(commits) < limit) {
+		commits = append(commits, commit)
+		commit = commit.Parent(0)
+	}
+	return commits, nil
+}
+
+// The abbreviated form of a commit ID shown to users.
+func ShortID(commit *git.Commit) string {
+	return commit.Id().String()[:7]
+}
+
+// Returns the commits on the current line after the given commit, oldest first.
+// Returns an error if the commit isn't on the current line.
+func commitsAfter(ancestor *git.Commit, repo *git.Repository) ([]*git.Commit, error) {
+	commit, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*git.Commit
+	for !commit.Id().Equal(ancestor.Id()) {
+		commits = append([]*git.Commit{commit}, commits...)
+		commit = commit.Parent(0)
+		if commit == nil {
+			return nil, errors.New("Commit " + ShortID(ancestor) + " is not on the current line.")
+		}
+	}
+	return commits, nil
+}
+
+// Returns the number of commits on the current line since it branched from the given line,
+// that is, since the last commit the two have in common.
+func CountCommitsSince(line string, repo *git.Repository) (int, error) {
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return 0, err
+	}
+	other, err := GetCommit(line, repo)
+	if err != nil {
+		return 0, err
+	}
+	baseID, err := repo.MergeBase(head.Id(), other.Id())
+	if err != nil {
+		return 0, err
+	}
+	base, err := repo.LookupCommit(baseID)
+	if err != nil {
+		return 0, err
+	}
+
+	commits, err := commitsAfter(base, repo)
+	if err != nil {
+		return 0, errors.New("The current line didn't branch from " + line + ".")
+	}
+	return len(commits), nil
+}
+
+// Returns the commits on the current line after from, up to and including to, oldest first.
+// Returns an error if either commit isn't on the current line or to comes before from.
+func CommitRange(from string, to string, repo *git.Repository) ([]*git.Commit, error) {
+	toCommit, err := GetCommit(to, repo)
+	if err != nil {
+		return nil, err
+	}
+	_, err = commitsAfter(toCommit, repo)
+	if err != nil {
+		return nil, err
+	}
+	return CommitsBetween(from, to, repo)
+}
+
+// Returns the commits after from, up to and including to, oldest first, following the line that to is on.
+// Returns an error if from doesn't come before to on that line.
+func CommitsBetween(from string, to string, repo *git.Repository) ([]*git.Commit, error) {
+	fromCommit, err := GetCommit(from, repo)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := GetCommit(to, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*git.Commit
+	for commit := toCommit; !commit.Id().Equal(fromCommit.Id()); commit = commit.Parent(0) {
+		if commit.ParentCount() == 0 {
+			return nil, errors.New("Commit " + ShortID(fromCommit) + " doesn't come before " + ShortID(toCommit) + " on its line.")
+		}
+		commits = append([]*git.Commit{commit}, commits...)
+	}
+	if len(commits) == 0 {
+		return nil, errors.New("There are no commits between " + from + " and " + to + ".")
+	}
+	return commits, nil
+}
@@ -0,0 +1,405 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Patterns that usually match generated files, which new users often commit by accident.
+var generatedPatterns = []string{
+	"bin/", "build/", "dist/", "out/", "target/", "node_modules/", "__pycache__/",
+	"*.o", "*.obj", "*.a", "*.so", "*.dll", "*.exe", "*.class", "*.pyc", "*.log",
+}
+
+// A single rule from an ignore file.
+type IgnoreRule struct {
+	// The file the rule was read from, relative to the repo directory.
+	Source string
+	// The line number of the rule in its file.
+	Line int
+	// The rule as written in the file.
+	Pattern string
+
+	// The directory containing the ignore file, relative to the repo directory.
+	base     string
+	negated  bool
+	dirOnly  bool
+	anchored bool
+	glob     string
+}
+
+// Loads and caches the ignore rules that apply to paths in the repo.
+type ignoreMatcher struct {
+	repo   *git.Repository
+	global []IgnoreRule
+	perDir map[string][]IgnoreRule
+}
+
+// Returns the rules in the .gitignore file at the root of the repo.
+func IgnoreRules(repo *git.Repository) ([]IgnoreRule, error) {
+	return readIgnoreFile(filepath.Join(repo.Workdir(), ".gitignore"), ".gitignore", "")
+}
+
+// Adds a pattern to the end of the .gitignore file at the root of the repo.
+func AddIgnoreRule(pattern string, repo *git.Repository) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return errors.New("Invalid ignore pattern.")
+	}
+
+	rules, err := IgnoreRules(repo)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Pattern == pattern {
+			return errors.New("Already ignoring " + pattern + ".")
+		}
+	}
+
+	file := filepath.Join(repo.Workdir(), ".gitignore")
+	dat, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(dat)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return ioutil.WriteFile(file, []byte(content+pattern+"\n"), 0644)
+}
+
+// Removes every occurrence of a pattern from the .gitignore file at the root of the repo.
+func RemoveIgnoreRule(pattern string, repo *git.Repository) error {
+	pattern = strings.TrimSpace(pattern)
+	file := filepath.Join(repo.Workdir(), ".gitignore")
+	dat, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	found := false
+	var kept []string
+	for _, line := range strings.SplitAfter(string(dat), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			found = true
+		} else if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	if !found {
+		return errors.New("No ignore rule " + pattern + ".")
+	}
+
+	return ioutil.WriteFile(file, []byte(strings.Join(kept, "")), 0644)
+}
+
+// Finds the rule that causes the given path to be ignored.
+// The path is relative to the repo directory.
+// Returns nil if the path is not ignored.
+func ExplainIgnored(file string, repo *git.Repository) (*IgnoreRule, error) {
+	matcher, err := newIgnoreMatcher(repo)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(repo.Workdir(), file))
+	isDir := err == nil && info.IsDir()
+	return matcher.match(filepath.ToSlash(filepath.Clean(file)), isDir)
+}
+
+// Returns the paths of all tracked files that match an ignore rule.
+// These will keep being committed until they are untracked.
+func TrackedIgnored(repo *git.Repository) ([]string, error) {
+	matcher, err := newIgnoreMatcher(repo)
+	if err != nil {
+		return nil, err
+	}
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for i := uint(0); i < index.EntryCount(); i++ {
+		entry, err := index.EntryByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := matcher.match(entry.Path, false)
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths, nil
+}
+
+// Removes all tracked files that match an ignore rule from the index, leaving them in the working directory.
+// The files will be removed from the line by the next commit.
+// Returns the paths of the untracked files.
+func UntrackIgnored(repo *git.Repository) ([]string, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := TrackedIgnored(repo)
+	if err != nil {
+		return nil, err
+	}
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		err = index.RemoveByPath(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = index.Write()
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Returns the new files and directories that look like build output,
+// and would be included in the next commit.
+func LikelyGenerated(repo *git.Repository) ([]string, error) {
+	statusOps := git.StatusOptions{
+		Show:  git.StatusShowIndexAndWorkdir,
+		Flags: git.StatusOptIncludeUntracked,
+	}
+	status, err := repo.StatusList(&statusOps)
+	if err != nil {
+		return nil, err
+	}
+	count, err := status.EntryCount()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	seen := map[string]bool{}
+	for i := 0; i < count; i++ {
+		entry, err := status.ByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		// New files may already be staged, or still untracked.
+		var p string
+		if entry.Status&git.StatusIndexNew != 0 {
+			p = entry.HeadToIndex.NewFile.Path
+		} else if entry.Status&git.StatusWtNew != 0 {
+			p = entry.IndexToWorkdir.NewFile.Path
+		} else {
+			continue
+		}
+		if generated := generatedPrefix(p); generated != "" && !seen[generated] {
+			seen[generated] = true
+			paths = append(paths, generated)
+		}
+	}
+	return paths, nil
+}
+
+// Returns the part of a path that matches one of the generatedPatterns,
+// which may be a directory containing it, or "" if there is no match.
+// Directory paths end with a slash.
+func generatedPrefix(p string) string {
+	parts := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		prefix := strings.Join(parts[:i], "/")
+		isDir := i < len(parts) || strings.HasSuffix(p, "/")
+		for _, pattern := range generatedPatterns {
+			if parseIgnoreRule(pattern, "", "", 0).matches(prefix, isDir) {
+				if isDir {
+					return prefix + "/"
+				}
+				return prefix
+			}
+		}
+	}
+	return ""
+}
+
+func newIgnoreMatcher(repo *git.Repository) (*ignoreMatcher, error) {
+	matcher := &ignoreMatcher{repo: repo, perDir: map[string][]IgnoreRule{}}
+
+	// Rules from the user's global excludes file and the repo's exclude file apply everywhere,
+	// but with lower priority than any .gitignore file.
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	excludesFile, err := config.LookupString("core.excludesfile")
+	if err == nil && excludesFile != "" {
+		if strings.HasPrefix(excludesFile, "~/") {
+			excludesFile = filepath.Join(os.Getenv("HOME"), excludesFile[2:])
+		}
+		rules, err := readIgnoreFile(excludesFile, excludesFile, "")
+		if err != nil {
+			return nil, err
+		}
+		matcher.global = append(matcher.global, rules...)
+	}
+	rules, err := readIgnoreFile(filepath.Join(repo.Path(), "info", "exclude"), ".git/info/exclude", "")
+	if err != nil {
+		return nil, err
+	}
+	matcher.global = append(matcher.global, rules...)
+
+	return matcher, nil
+}
+
+// Returns the rule that ignores the given slash-separated path, or nil if it isn't ignored.
+// A path inside an ignored directory is always ignored.
+func (m *ignoreMatcher) match(file string, isDir bool) (*IgnoreRule, error) {
+	parts := strings.Split(file, "/")
+	for i := 1; i < len(parts); i++ {
+		rule, err := m.matchSingle(strings.Join(parts[:i], "/"), true)
+		if err != nil || rule != nil {
+			return rule, err
+		}
+	}
+	return m.matchSingle(file, isDir)
+}
+
+// Returns the rule that ignores the given path, without considering its parent directories.
+func (m *ignoreMatcher) matchSingle(file string, isDir bool) (*IgnoreRule, error) {
+	// Rules later in the list take priority, so the deepest .gitignore files come last.
+	dirs := []string{""}
+	if parent := path.Dir(file); parent != "." {
+		parts := strings.Split(parent, "/")
+		for i := 1; i <= len(parts); i++ {
+			dirs = append(dirs, strings.Join(parts[:i], "/"))
+		}
+	}
+	rules := append([]IgnoreRule{}, m.global...)
+	for _, dir := range dirs {
+		dirRules, err := m.dirRules(dir)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, dirRules...)
+	}
+
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(file, isDir) {
+			if rules[i].negated {
+				return nil, nil
+			}
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the rules in the .gitignore file of the given directory, relative to the repo directory.
+func (m *ignoreMatcher) dirRules(dir string) ([]IgnoreRule, error) {
+	rules, ok := m.perDir[dir]
+	if !ok {
+		source := path.Join(dir, ".gitignore")
+		var err error
+		rules, err = readIgnoreFile(filepath.Join(m.repo.Workdir(), filepath.FromSlash(source)), source, dir)
+		if err != nil {
+			return nil, err
+		}
+		m.perDir[dir] = rules
+	}
+	return rules, nil
+}
+
+// Reads the rules from an ignore file. A missing file has no rules.
+// file - The path of the file to read
+// source - The name to report the file by
+// base - The directory the rules are relative to, within the repo
+func readIgnoreFile(file string, source string, base string) ([]IgnoreRule, error) {
+	dat, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []IgnoreRule
+	for i, line := range strings.Split(string(dat), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, parseIgnoreRule(line, source, base, i+1))
+	}
+	return rules, nil
+}
+
+func parseIgnoreRule(pattern string, source string, base string, line int) IgnoreRule {
+	rule := IgnoreRule{Source: source, Line: line, Pattern: pattern, base: base}
+
+	glob := pattern
+	if strings.HasPrefix(glob, "!") {
+		rule.negated = true
+		glob = glob[1:]
+	}
+	glob = strings.TrimPrefix(glob, "\\")
+	if strings.HasSuffix(glob, "/") {
+		rule.dirOnly = true
+		glob = strings.TrimSuffix(glob, "/")
+	}
+	// A pattern containing a slash only matches relative to the directory of its ignore file.
+	// Otherwise it matches a file or directory name at any depth.
+	if strings.Contains(glob, "/") {
+		rule.anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	rule.glob = glob
+	return rule
+}
+
+// Whether the rule matches the given slash-separated path, relative to the repo directory.
+func (r IgnoreRule) matches(file string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(file, r.base+"/") {
+			return false
+		}
+		file = file[len(r.base)+1:]
+	}
+
+	if !r.anchored {
+		matched, _ := path.Match(r.glob, path.Base(file))
+		return matched
+	}
+	return matchSegments(strings.Split(r.glob, "/"), strings.Split(file, "/"))
+}
+
+// Match path segments against glob segments, where a ** segment matches any number of path segments.
+func matchSegments(globs []string, parts []string) bool {
+	if len(globs) == 0 {
+		return len(parts) == 0
+	}
+	if globs[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(globs[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	matched, _ := path.Match(globs[0], parts[0])
+	return matched && matchSegments(globs[1:], parts[1:])
+}
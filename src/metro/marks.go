@@ -0,0 +1,188 @@
+package metro
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	git "github.com/libgit2/git2go"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Marks are stored as annotated tags under this namespace.
+const markPrefix = "refs/tags/"
+
+// The start of the signature appended to a signed mark's message.
+const pgpSignatureStart = "-----BEGIN PGP SIGNATURE-----"
+
+// A named commit, such as a release or milestone.
+type Mark struct {
+	Name string
+	// The commit the mark points to.
+	Commit *git.Oid
+	// The message of an annotated mark, without its signature.
+	Message string
+	// Who made an annotated mark, or nil for plain git tags.
+	Tagger *git.Signature
+	Signed bool
+}
+
+// Mark a commit with an annotated tag, optionally signed with the user's GPG key.
+// Returns the commit that was marked.
+func CreateMark(name string, revision string, message string, sign bool, repo *git.Repository) (*git.Commit, error) {
+	if !git.ReferenceIsValidName(markPrefix + name) {
+		return nil, errors.New("Invalid mark name: " + name)
+	}
+	// These would be taken as metro mark's subcommands.
+	if name == "list" || name == "delete" {
+		return nil, errors.New("A mark can't be called " + name + ", as metro mark " + name + " is a command.")
+	}
+	if _, err := repo.References.Lookup(markPrefix + name); err == nil {
+		return nil, errors.New("There is already a mark called " + name + ".")
+	}
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return nil, err
+	}
+	tagger, err := userSignature(repo)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		message = name
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	if !sign {
+		_, err = repo.Tags.Create(name, commit, tagger, message)
+		return commit, err
+	}
+
+	// libgit2 can't sign tags, so the tag object is written by hand with a signature from gpg, as git does.
+	_, offset := tagger.When.Zone()
+	zoneSign := "+"
+	if offset < 0 {
+		zoneSign = "-"
+		offset = -offset
+	}
+	content := fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s <%s> %d %s%02d%02d\n\n%s",
+		commit.Id().String(), name, tagger.Name, tagger.Email, tagger.When.Unix(), zoneSign, offset/3600, (offset%3600)/60, message)
+	signature, err := gpgSign(content, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	odb, err := repo.Odb()
+	if err != nil {
+		return nil, err
+	}
+	tagID, err := odb.Write([]byte(content+signature), git.ObjectTag)
+	if err != nil {
+		return nil, err
+	}
+	_, err = repo.References.Create(markPrefix+name, tagID, false, "metro: mark "+name)
+	return commit, err
+}
+
+// Sign content with the user's GPG key, configured by user.signingKey, returning an armored detached signature.
+func gpgSign(content string, repo *git.Repository) (string, error) {
+	program, err := configString("gpg.program", repo)
+	if err != nil {
+		return "", err
+	}
+	if program == "" {
+		program = "gpg"
+	}
+	key, err := configString("user.signingKey", repo)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		signature, err := userSignature(repo)
+		if err != nil {
+			return "", err
+		}
+		key = signature.Name + " <" + signature.Email + ">"
+	}
+
+	cmd := exec.Command(program, "-bsau", key)
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", errors.New("Signing the mark failed: " + strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Returns all marks in the repo, sorted by name.
+func Marks(repo *git.Repository) ([]Mark, error) {
+	iterator, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+
+	var marks []Mark
+	for {
+		ref, err := iterator.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(ref.Name(), markPrefix) {
+			continue
+		}
+
+		// Tags of anything other than commits aren't marks.
+		target, err := ref.Peel(git.ObjectCommit)
+		if err != nil {
+			continue
+		}
+		mark := Mark{Name: strings.TrimPrefix(ref.Name(), markPrefix), Commit: target.Id()}
+		if tag, err := repo.LookupTag(ref.Target()); err == nil {
+			mark.Tagger = tag.Tagger()
+			mark.Message = tag.Message()
+			if index := strings.Index(mark.Message, pgpSignatureStart); index >= 0 {
+				mark.Message = mark.Message[:index]
+				mark.Signed = true
+			}
+		}
+		marks = append(marks, mark)
+	}
+
+	sort.Slice(marks, func(i, j int) bool {
+		return marks[i].Name < marks[j].Name
+	})
+	return marks, nil
+}
+
+// Returns the names of the marks on each commit, by commit ID.
+func MarksByCommit(repo *git.Repository) (map[string][]string, error) {
+	marks, err := Marks(repo)
+	if err != nil {
+		return nil, err
+	}
+	byCommit := map[string][]string{}
+	for _, mark := range marks {
+		id := mark.Commit.String()
+		byCommit[id] = append(byCommit[id], mark.Name)
+	}
+	return byCommit, nil
+}
+
+// Deletes a mark. The commit it marked is unaffected.
+func DeleteMark(name string, repo *git.Repository) error {
+	ref, err := repo.References.Lookup(markPrefix + name)
+	if err != nil {
+		return errors.New("No mark called " + name + ".")
+	}
+	return ref.Delete()
+}
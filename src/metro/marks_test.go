@@ -0,0 +1,41 @@
+package metro
+
+import (
+	"testing"
+)
+
+func TestCreateMark(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Second", map[string]string{"a.txt": "a\n"})
+
+	// A mark named after a line still reports the commit it marked, not the line.
+	marked, err := CreateMark(main, first.Id().String(), "", false, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !marked.Id().Equal(first.Id()) {
+		t.Errorf("CreateMark returned %s, want %s", marked.Id(), first.Id())
+	}
+	marks, err := Marks(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marks) != 1 || marks[0].Name != main || !marks[0].Commit.Equal(first.Id()) {
+		t.Errorf("Marks = %+v, want %s on %s", marks, main, first.Id())
+	}
+
+	for _, name := range []string{main, "list", "delete", "bad..name"} {
+		if _, err := CreateMark(name, "HEAD", "", false, repo); err == nil {
+			t.Errorf("CreateMark(%q) succeeded, want error", name)
+		}
+	}
+}
@@ -12,10 +12,22 @@ func Absorb(mergeHead string, repo *git.Repository) (bool, error) {
 		return false, errors.New("Can't absorb WIP branch.")
 	}
 
-	err := AssertMerging(repo)
+	err := assertOnLine(repo)
 	if err != nil {
 		return false, err
 	}
+	err = AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	// The start of a line's name is enough if it isn't already a revision.
+	line, err := ResolveLine(mergeHead, repo)
+	if err != nil {
+		return false, err
+	}
+	if line != "" {
+		mergeHead = line
+	}
 
 	err = startMerge(mergeHead, repo)
 	if err != nil {
@@ -31,7 +43,7 @@ func Absorb(mergeHead string, repo *git.Repository) (bool, error) {
 		return true, nil
 	} else {
 		// If no conflicts occurred make the merge commit right away.
-		err = Resolve(repo)
+		err = Resolve(repo, "")
 		if err != nil {
 			return false, err
 		}
@@ -84,7 +96,12 @@ func startMerge(name string, repo *git.Repository) error {
 }
 
 // Create a commit of the ongoing merge and clear the merge state and conflicts from the repo.
-func Resolve(repo *git.Repository) error {
+// If message is empty the merge message is used.
+func Resolve(repo *git.Repository, message string) error {
+	err := assertOnLine(repo)
+	if err != nil {
+		return err
+	}
 	merging := MergeOngoing(repo)
 	if !merging {
 		return errors.New("You can only resolve conflicts while absorbing.")
@@ -95,9 +112,11 @@ func Resolve(repo *git.Repository) error {
 		return err
 	}
 
-	message, err := getMergeMessage(repo)
-	if err != nil {
-		return err
+	if message == "" {
+		message, err = MergeMessage(repo)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Remove merge state.
@@ -136,7 +155,7 @@ func defaultMergeMessage(mergedName string) string {
 }
 
 // Load the merge message from the repo's MERGE_MSG file.
-func getMergeMessage(repo *git.Repository) (string, error) {
+func MergeMessage(repo *git.Repository) (string, error) {
 	dat, err := ioutil.ReadFile(repo.Path() + "/MERGE_MSG")
 	if err != nil {
 		return "", err
@@ -0,0 +1,49 @@
+package metro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAbsorbWhileViewing(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on feature", map[string]string{"feature.txt": "feature\n"})
+	err = SwitchBranch(main, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewed := commitTestFiles(t, repo, "Work on "+main, map[string]string{"main.txt": "main\n"})
+	err = SwitchToCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Absorb("feature", repo)
+	if err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Absorb while viewing a commit = %v, want a read-only error", err)
+	}
+	if MergeOngoing(repo) {
+		t.Error("A refused absorb started a merge")
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !head.Id().Equal(viewed.Id()) {
+		t.Error("A refused absorb made a commit")
+	}
+}
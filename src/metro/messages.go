@@ -0,0 +1,132 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Config keys for the rules commit messages must follow.
+const (
+	maxSubjectLengthKey = "metro.maxSubjectLength"
+	issuePatternKey     = "metro.issuePattern"
+	commitTypesKey      = "metro.commitTypes"
+	messageTemplateKey  = "metro.messageTemplate"
+)
+
+// Matches a Conventional Commits subject, capturing its type.
+var conventionalSubject = regexp.MustCompile(`^([a-zA-Z]+)(\([^()]+\))?!?: \S`)
+
+// The rules commit messages must follow, loaded from the repo's config.
+// Zero values mean the rule is disabled.
+type MessagePolicy struct {
+	// Maximum number of characters in the first line of the message.
+	MaxSubjectLength int
+	// Regular expression that must match somewhere in the message, e.g. "[A-Z]+-[0-9]+".
+	IssuePattern *regexp.Regexp
+	// Conventional Commits types the subject must start with, e.g. "feat" or "fix".
+	Types []string
+}
+
+// Load the message policy from the repo's config.
+func LoadMessagePolicy(repo *git.Repository) (MessagePolicy, error) {
+	var policy MessagePolicy
+	var err error
+
+	policy.MaxSubjectLength, err = configInt(maxSubjectLengthKey, 0, repo)
+	if err != nil {
+		return policy, err
+	}
+	issuePattern, err := configString(issuePatternKey, repo)
+	if err != nil {
+		return policy, err
+	}
+	if issuePattern != "" {
+		policy.IssuePattern, err = regexp.Compile(issuePattern)
+		if err != nil {
+			return policy, errors.New("Invalid " + issuePatternKey + " in config: " + err.Error())
+		}
+	}
+	types, err := configString(commitTypesKey, repo)
+	if err != nil {
+		return policy, err
+	}
+	for _, t := range strings.Split(types, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			policy.Types = append(policy.Types, t)
+		}
+	}
+
+	return policy, nil
+}
+
+// Checks a commit message against the repo's message policy.
+// Returns an error describing the first rule the message breaks.
+func CheckMessage(message string, repo *git.Repository) error {
+	policy, err := LoadMessagePolicy(repo)
+	if err != nil {
+		return err
+	}
+	return policy.Check(message)
+}
+
+// Checks a commit message against the policy.
+// Returns an error describing the first rule the message breaks.
+func (policy MessagePolicy) Check(message string) error {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return errors.New("Commit message can't be empty.")
+	}
+	subject := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+
+	if policy.MaxSubjectLength > 0 && len([]rune(subject)) > policy.MaxSubjectLength {
+		return errors.New("The first line of the commit message is " + strconv.Itoa(len([]rune(subject))) +
+			" characters long, but can be at most " + strconv.Itoa(policy.MaxSubjectLength) + ".")
+	}
+
+	if len(policy.Types) > 0 {
+		match := conventionalSubject.FindStringSubmatch(subject)
+		if match == nil {
+			return errors.New("The commit message must start with a type, like \"" + policy.Types[0] + ": Describe the change\".\n" +
+				"Allowed types: " + strings.Join(policy.Types, ", "))
+		}
+		allowed := false
+		for _, t := range policy.Types {
+			if match[1] == t {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.New("Unknown commit type " + match[1] + ".\nAllowed types: " + strings.Join(policy.Types, ", "))
+		}
+	}
+
+	if policy.IssuePattern != nil && !policy.IssuePattern.MatchString(message) {
+		return errors.New("The commit message must mention an issue matching " + policy.IssuePattern.String() + ".")
+	}
+
+	return nil
+}
+
+// Returns the contents of the commit message template set in the repo's config,
+// or "" if there is none. A relative template path is relative to the repo directory.
+func MessageTemplate(repo *git.Repository) (string, error) {
+	file, err := configString(messageTemplateKey, repo)
+	if err != nil || file == "" {
+		return "", err
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(repo.Workdir(), file)
+	}
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(dat), nil
+}
@@ -0,0 +1,64 @@
+package metro
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMessagePolicyCheck(t *testing.T) {
+	policy := MessagePolicy{
+		MaxSubjectLength: 20,
+		IssuePattern:     regexp.MustCompile("[A-Z]+-[0-9]+"),
+		Types:            []string{"feat", "fix"},
+	}
+
+	tests := []struct {
+		message string
+		// Part of the expected error, or "" if the message should pass.
+		err string
+	}{
+		{"feat: Add X ABC-1", ""},
+		{"fix(parser)!: Fix Y\n\nFixes ABC-12", ""},
+		{"   \n", "can't be empty"},
+		{"feat: A subject that is far too long ABC-1", "at most 20"},
+		{"Add X ABC-1", "must start with a type"},
+		{"docs: Add X ABC-1", "Unknown commit type docs"},
+		{"feat: Add X", "must mention an issue"},
+	}
+	for _, test := range tests {
+		err := policy.Check(test.message)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("Check(%q) = %v, want no error", test.message, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Check(%q) = %v, want error containing %q", test.message, err, test.err)
+		}
+	}
+}
+
+func TestEmptyMessagePolicyAllowsAnyMessage(t *testing.T) {
+	var policy MessagePolicy
+	for _, message := range []string{"x", "Absorbed feature into master", strings.Repeat("a", 500)} {
+		if err := policy.Check(message); err != nil {
+			t.Errorf("Check(%q) = %v, want no error", message, err)
+		}
+	}
+}
+
+func TestMessagePolicyInvalidIssuePattern(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	err := setConfigString(issuePatternKey, "[", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadMessagePolicy(repo)
+	if err == nil || !strings.Contains(err.Error(), issuePatternKey) {
+		t.Errorf("LoadMessagePolicy with an invalid pattern = %v, want error naming %s", err, issuePatternKey)
+	}
+}
@@ -0,0 +1,171 @@
+package metro
+
+import (
+	"encoding/json"
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// The ref holding the metadata of every line, so that it can be synced like any other ref.
+const metadataRef = "refs/metro/metadata"
+
+// The file in the metadata ref's tree holding the metadata.
+const metadataFile = "lines.json"
+
+// The statuses a line can have.
+var LineStatuses = []string{"active", "review", "done"}
+
+// What a line is for, stored in the metadata ref.
+type LineMetadata struct {
+	Description string   `json:",omitempty"`
+	Owner       string   `json:",omitempty"`
+	Issues      []string `json:",omitempty"`
+	// One of LineStatuses, or "" if not set.
+	Status string `json:",omitempty"`
+}
+
+// Returns true if none of the metadata is set.
+func (m LineMetadata) Empty() bool {
+	return m.Description == "" && m.Owner == "" && len(m.Issues) == 0 && m.Status == ""
+}
+
+// Returns the metadata of every line that has any, by line name.
+func AllLineMetadata(repo *git.Repository) (map[string]LineMetadata, error) {
+	return lineMetadataAt(metadataRef, repo)
+}
+
+// Returns the metadata held by the given ref, which is empty if the ref doesn't exist.
+func lineMetadataAt(refName string, repo *git.Repository) (map[string]LineMetadata, error) {
+	metadata := map[string]LineMetadata{}
+	ref, err := repo.References.Lookup(refName)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return metadata, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := repo.LookupCommit(ref.Target())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	entry := tree.EntryByName(metadataFile)
+	if entry == nil {
+		return metadata, nil
+	}
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(blob.Contents(), &metadata)
+	if err != nil {
+		return nil, errors.New("The line metadata is corrupted: " + err.Error())
+	}
+	return metadata, nil
+}
+
+// Returns the metadata of a line, which is empty if none has been set.
+func GetLineMetadata(name string, repo *git.Repository) (LineMetadata, error) {
+	metadata, err := AllLineMetadata(repo)
+	if err != nil {
+		return LineMetadata{}, err
+	}
+	return metadata[name], nil
+}
+
+// Set the metadata of a line, replacing any it had before.
+func SetLineMetadata(name string, metadata LineMetadata, repo *git.Repository) error {
+	if metadata.Status != "" {
+		valid := false
+		for _, status := range LineStatuses {
+			if metadata.Status == status {
+				valid = true
+			}
+		}
+		if !valid {
+			return errors.New("Line status must be one of " + strings.Join(LineStatuses, ", ") + ".")
+		}
+	}
+
+	all, err := AllLineMetadata(repo)
+	if err != nil {
+		return err
+	}
+	if metadata.Empty() {
+		delete(all, name)
+	} else {
+		all[name] = metadata
+	}
+	return saveLineMetadata(all, "metro: describe "+name, repo)
+}
+
+// Move the metadata of a renamed line to its new name.
+func renameLineMetadata(oldName string, newName string, repo *git.Repository) error {
+	all, err := AllLineMetadata(repo)
+	if err != nil {
+		return err
+	}
+	metadata, ok := all[oldName]
+	if !ok {
+		return nil
+	}
+	delete(all, oldName)
+	all[newName] = metadata
+	return saveLineMetadata(all, "metro: rename "+oldName+" to "+newName, repo)
+}
+
+// Commit the metadata of every line to the metadata ref, on top of its previous contents.
+// Metadata merged from elsewhere is given as further parents.
+func saveLineMetadata(metadata map[string]LineMetadata, message string, repo *git.Repository, merged ...*git.Commit) error {
+	dat, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	blobID, err := repo.CreateBlobFromBuffer(append(dat, '\n'))
+	if err != nil {
+		return err
+	}
+	builder, err := repo.TreeBuilder()
+	if err != nil {
+		return err
+	}
+	defer builder.Free()
+	err = builder.Insert(metadataFile, blobID, git.FilemodeBlob)
+	if err != nil {
+		return err
+	}
+	treeID, err := builder.Write()
+	if err != nil {
+		return err
+	}
+	tree, err := repo.LookupTree(treeID)
+	if err != nil {
+		return err
+	}
+
+	var parents []*git.Commit
+	ref, err := repo.References.Lookup(metadataRef)
+	if err == nil {
+		parent, err := repo.LookupCommit(ref.Target())
+		if err != nil {
+			return err
+		}
+		parents = append(parents, parent)
+	} else if !git.IsErrorCode(err, git.ErrNotFound) {
+		return err
+	}
+
+	parents = append(parents, merged...)
+
+	signature, err := userSignature(repo)
+	if err != nil {
+		return err
+	}
+	_, err = repo.CreateCommit(metadataRef, signature, signature, message, tree, parents...)
+	return err
+}
@@ -0,0 +1,50 @@
+package metro
+
+import (
+	"testing"
+)
+
+func TestSetLineMetadata(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	metadata, err := GetLineMetadata("feature", repo)
+	if err != nil || !metadata.Empty() {
+		t.Errorf("GetLineMetadata before any is set = %+v, %v, want none", metadata, err)
+	}
+
+	want := LineMetadata{Description: "A feature", Owner: "someone", Issues: []string{"#12"}, Status: "review"}
+	err = SetLineMetadata("feature", want, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err = GetLineMetadata("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Description != want.Description || metadata.Owner != want.Owner || metadata.Status != want.Status ||
+		len(metadata.Issues) != 1 || metadata.Issues[0] != "#12" {
+		t.Errorf("GetLineMetadata = %+v, want %+v", metadata, want)
+	}
+
+	if err := SetLineMetadata("feature", LineMetadata{Status: "finished"}, repo); err == nil {
+		t.Error("SetLineMetadata with an unknown status succeeded, want error")
+	}
+	metadata, err = GetLineMetadata("feature", repo)
+	if err != nil || metadata.Status != "review" {
+		t.Errorf("A refused SetLineMetadata changed the metadata to %+v, %v", metadata, err)
+	}
+
+	// Setting empty metadata removes the line's entry.
+	err = SetLineMetadata("feature", LineMetadata{}, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := AllLineMetadata(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all["feature"]; ok {
+		t.Error("Clearing a line's metadata left its entry")
+	}
+}
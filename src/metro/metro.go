@@ -23,9 +23,9 @@ func Create(directory string) (*git.Repository, error) {
 	return repo, nil
 }
 
-// Raises an error if the repo is currently in merging state.
+// Raises an error if the repo is currently in merging state, or waiting for conflicts to be resolved during a replay.
 func AssertMerging(repo *git.Repository) error {
-	if MergeOngoing(repo) {
+	if MergeOngoing(repo) || ReplayOngoing(repo) {
 		return errors.New("Branch has conflicts, please finish resolving them.\nRun metro resolve when you are done.")
 	}
 	return nil
@@ -0,0 +1,138 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// Moves commits of the current line to the end of another line, then removes them from the current line.
+// Any commits after them on the current line are replayed in their place.
+// Returns true if replaying those commits stopped with conflicts, ContinueReplay then finishes it.
+// commits - Commits on the current line to move, oldest first, with no gaps between them
+// line - Name of the line to move them to
+func MoveCommits(commits []*git.Commit, line string, repo *git.Repository) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertNoChanges(repo)
+	if err != nil {
+		return false, err
+	}
+	if strings.HasSuffix(line, WipString) {
+		return false, errors.New("Can't move commits to a wip line.")
+	}
+	current, err := currentLine(repo)
+	if err != nil {
+		return false, err
+	}
+	if line == current {
+		return false, errors.New("The commits are already on " + line + ".")
+	}
+	target, err := repo.LookupBranch(line, git.BranchLocal)
+	if err != nil {
+		return false, errors.New("No line called " + line + ".")
+	}
+	err = assertUnprotected(current, repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertLineCanCommit(line, repo)
+	if err != nil {
+		return false, err
+	}
+	// The line's WIP was saved against its old tip, restoring it would undo the moved commits.
+	if CommitExists(line+WipString, repo) {
+		return false, errors.New("Line " + line + " has work in progress, switch to it and commit it before moving commits to it.")
+	}
+
+	oldest := commits[0]
+	newest := commits[len(commits)-1]
+	base := oldest.Parent(0)
+	if base == nil {
+		return false, errors.New("The first commit of a line can't be moved.")
+	}
+	later, err := commitsAfter(newest, repo)
+	if err != nil {
+		return false, err
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+
+	tip, err := repo.LookupCommit(target.Target())
+	if err != nil {
+		return false, err
+	}
+	for _, commit := range commits {
+		tip, err = copyCommit(commit, tip, repo)
+		if err != nil {
+			return false, err
+		}
+	}
+	_, err = target.SetTarget(tip.Id(), "metro: move commits from "+current)
+	if err != nil {
+		return false, err
+	}
+
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	err = repo.ResetToCommit(base, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return false, err
+	}
+	return replay("move", head.Id(), nil, pickSteps(later), repo)
+}
+
+// Recreates a commit on top of another without touching the working directory,
+// keeping its author and message. An absorb commit stays an absorb of the same commits.
+// Returns an error if the commit's changes conflict with the new parent.
+func copyCommit(commit *git.Commit, onto *git.Commit, repo *git.Repository) (*git.Commit, error) {
+	ancestorTree, err := commit.Parent(0).Tree()
+	if err != nil {
+		return nil, err
+	}
+	ontoTree, err := onto.Tree()
+	if err != nil {
+		return nil, err
+	}
+	commitTree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	mergeOptions, err := git.DefaultMergeOptions()
+	if err != nil {
+		return nil, err
+	}
+	index, err := repo.MergeTrees(ancestorTree, ontoTree, commitTree, &mergeOptions)
+	if err != nil {
+		return nil, err
+	}
+	if index.HasConflicts() {
+		return nil, errors.New("Commit " + ShortID(commit) + " conflicts with the commits it would follow, so it can't be moved.")
+	}
+	oid, err := index.WriteTreeTo(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.LookupTree(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := []*git.Commit{onto}
+	for i := uint(1); i < commit.ParentCount(); i++ {
+		parents = append(parents, commit.Parent(i))
+	}
+	committer, err := userSignature(repo)
+	if err != nil {
+		return nil, err
+	}
+	copiedID, err := repo.CreateCommit("", commit.Author(), committer, commit.Message(), tree, parents...)
+	if err != nil {
+		return nil, err
+	}
+	return repo.LookupCommit(copiedID)
+}
@@ -0,0 +1,88 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"strings"
+	"testing"
+)
+
+func TestMoveCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("other", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+
+	conflicts, err := MoveCommits([]*git.Commit{a}, "other", repo)
+	if err != nil || conflicts {
+		t.Fatalf("MoveCommits = %v, %v", conflicts, err)
+	}
+
+	commits, err := LineHistory(main, 2, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits[0].Summary() != "Add b" || commits[1].Summary() != "Create repository" {
+		t.Errorf("%s has %q, %q after the move", main, commits[0].Summary(), commits[1].Summary())
+	}
+	if testFileAt(t, commits[0], "a.txt", repo) != "" || testFileAt(t, commits[0], "b.txt", repo) != "b\n" {
+		t.Error("The commit after the moved one wasn't replayed without it")
+	}
+	moved, err := GetCommit("other", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Summary() != "Add a" || testFileAt(t, moved, "a.txt", repo) != "a\n" {
+		t.Error("The moved commit isn't at the end of the other line")
+	}
+	if moved.Author().When.Unix() != a.Author().When.Unix() || moved.Author().Email != a.Author().Email {
+		t.Error("MoveCommits changed the moved commit's author")
+	}
+}
+
+func TestMoveCommitsRefusesLineWithWip(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave work in progress on other.
+	_, err = CreateBranch("other", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch("other", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, repo, map[string]string{"o.txt": "unfinished\n"})
+	err = SwitchBranch(main, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+
+	_, err = MoveCommits([]*git.Commit{a}, "other", repo)
+	if err == nil || !strings.Contains(err.Error(), "work in progress") {
+		t.Errorf("MoveCommits to a line with a WIP = %v, want error", err)
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !head.Id().Equal(a.Id()) {
+		t.Error("A refused MoveCommits changed the current line")
+	}
+	if _, err := MoveCommits([]*git.Commit{a}, main, repo); err == nil {
+		t.Error("MoveCommits to the current line succeeded, want error")
+	}
+}
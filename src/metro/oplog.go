@@ -0,0 +1,461 @@
+package metro
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The most operations kept in the oplog, older ones are forgotten.
+const maxOperations = 1000
+
+// Refs under these prefixes are restored by undo and redo.
+var journaledRefPrefixes = []string{"refs/heads/", "refs/tags/", "refs/metro/"}
+
+// The commits, marks and working directories the oplog can restore are kept under this namespace as
+// refs/metro/oplog/<object ID>, so git gc doesn't remove them. Working directory trees are kept in commits of
+// their own, as refs to trees confuse git log --all.
+const oplogRefPrefix = "refs/metro/oplog/"
+
+// The parts of a repo's state that Metro operations change.
+type Snapshot struct {
+	// The commit ID of every journaled ref.
+	Refs map[string]string
+	// The ref HEAD points to, or the commit ID if HEAD is detached.
+	Head string
+	// The tree ID of the working directory contents,
+	// or "" if there were conflicts and the working directory couldn't be saved.
+	WIP string
+}
+
+// An entry in the oplog, recording the state of the repo before and after an operation.
+type Operation struct {
+	Name   string
+	Time   time.Time
+	Before Snapshot
+	After  Snapshot
+}
+
+func oplogFile(repo *git.Repository) string {
+	return filepath.Join(metroDir(repo), "oplog")
+}
+
+// The file storing how many operations in the oplog are currently applied; the rest have been undone.
+func oplogPositionFile(repo *git.Repository) string {
+	return filepath.Join(metroDir(repo), "oplog-position")
+}
+
+// Take a snapshot of the current state of the repo.
+// The working directory is saved without staging anything, so the index is left as it was.
+func TakeSnapshot(repo *git.Repository) (*Snapshot, error) {
+	snapshot := &Snapshot{Refs: map[string]string{}}
+
+	iterator, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+	for {
+		ref, err := iterator.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isJournaledRef(ref.Name()) && ref.Type() == git.ReferenceOid {
+			snapshot.Refs[ref.Name()] = ref.Target().String()
+		}
+	}
+
+	head, err := repo.References.Lookup("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if head.Type() == git.ReferenceSymbolic {
+		snapshot.Head = head.SymbolicTarget()
+	} else {
+		snapshot.Head = head.Target().String()
+	}
+
+	// Conflicts can't be stored in a tree, so the working directory is only saved without them.
+	index, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+	if !index.HasConflicts() {
+		tree, err := workingTree(repo)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.WIP = tree.Id().String()
+	}
+
+	return snapshot, nil
+}
+
+// Add an operation to the oplog, if it changed the state of the repo.
+// Any operations that were undone are forgotten, so they can no longer be redone.
+// name - Description of the operation shown to the user
+// before - Snapshot of the repo taken before the operation
+func RecordOperation(name string, before *Snapshot, repo *git.Repository) error {
+	after, err := TakeSnapshot(repo)
+	if err != nil {
+		return err
+	}
+	if before.equal(after) {
+		return nil
+	}
+
+	ops, position, err := loadOplog(repo)
+	if err != nil {
+		return err
+	}
+	ops = append(ops[:position], Operation{name, time.Now(), *before, *after})
+	if len(ops) > maxOperations {
+		ops = ops[len(ops)-maxOperations:]
+	}
+	err = keepOplogObjects(ops, repo)
+	if err != nil {
+		return err
+	}
+	return saveOplog(ops, len(ops), repo)
+}
+
+// Keep the objects the given operations can restore reachable from refs, and let go of any others kept before.
+// Only the values of refs an operation changed are needed: the others are either still current, or were
+// changed by another operation in the oplog.
+func keepOplogObjects(ops []Operation, repo *git.Repository) error {
+	needed := map[string]bool{}
+	for _, op := range ops {
+		for _, snapshot := range []Snapshot{op.Before, op.After} {
+			needed[snapshot.WIP] = true
+			for name := range snapshot.Refs {
+				if op.Before.Refs[name] != op.After.Refs[name] {
+					needed[snapshot.Refs[name]] = true
+				}
+			}
+		}
+	}
+	delete(needed, "")
+
+	kept, err := refsWithPrefix(oplogRefPrefix, repo)
+	if err != nil {
+		return err
+	}
+	for id := range kept {
+		if !needed[id] {
+			err = deleteRef(oplogRefPrefix+id, repo)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for id := range needed {
+		if _, ok := kept[id]; ok {
+			continue
+		}
+		oid, err := git.NewOid(id)
+		if err != nil {
+			return err
+		}
+		object, err := repo.Lookup(oid)
+		if err != nil {
+			// Already gone, so there's nothing left to keep.
+			continue
+		}
+		if object.Type() == git.ObjectTree {
+			tree, err := object.AsTree()
+			if err != nil {
+				return err
+			}
+			signature, err := userSignature(repo)
+			if err != nil {
+				return err
+			}
+			oid, err = repo.CreateCommit("", signature, signature, "metro: working directory kept by the oplog", tree)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = repo.References.Create(oplogRefPrefix+id, oid, true, "metro: keep for the oplog")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the operations in the oplog, oldest first, and how many of them are currently applied.
+func Oplog(repo *git.Repository) ([]Operation, int, error) {
+	return loadOplog(repo)
+}
+
+// Restore the repo to its state before the last operation that hasn't been undone.
+// Returns the undone operation.
+func Undo(repo *git.Repository) (*Operation, error) {
+	ops, position, err := loadOplog(repo)
+	if err != nil {
+		return nil, err
+	}
+	if position == 0 {
+		return nil, errors.New("Nothing to undo.")
+	}
+	op := ops[position-1]
+
+	err = assertUnchangedSince(op.After, op.Name, repo)
+	if err != nil {
+		return nil, err
+	}
+	err = assertRestoreKeepsProtected(op.After, op.Before, "undoing "+op.Name, repo)
+	if err != nil {
+		return nil, err
+	}
+	err = restoreSnapshot(op.Before, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &op, saveOplog(ops, position-1, repo)
+}
+
+// Repeat the last operation that was undone, restoring the repo to its state after the operation.
+// Returns the redone operation.
+func Redo(repo *git.Repository) (*Operation, error) {
+	ops, position, err := loadOplog(repo)
+	if err != nil {
+		return nil, err
+	}
+	if position == len(ops) {
+		return nil, errors.New("Nothing to redo.")
+	}
+	op := ops[position]
+
+	if op.After.WIP == "" {
+		return nil, errors.New("Can't redo " + op.Name + " because it stopped with conflicts, please run it again.")
+	}
+	err = assertUnchangedSince(op.Before, "undoing "+op.Name, repo)
+	if err != nil {
+		return nil, err
+	}
+	err = assertRestoreKeepsProtected(op.Before, op.After, "redoing "+op.Name, repo)
+	if err != nil {
+		return nil, err
+	}
+	err = restoreSnapshot(op.After, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &op, saveOplog(ops, position+1, repo)
+}
+
+// Raises an error if the repo has changed since the given snapshot was taken,
+// so that restoring another snapshot doesn't lose work.
+func assertUnchangedSince(snapshot Snapshot, name string, repo *git.Repository) error {
+	current, err := TakeSnapshot(repo)
+	if err != nil {
+		return err
+	}
+
+	if current.WIP != "" && snapshot.WIP != "" && current.WIP != snapshot.WIP {
+		return errors.New("You have made changes since " + name + ".\nCommit or discard them first.")
+	}
+	current.WIP = snapshot.WIP
+	if !current.equal(&snapshot) {
+		return errors.New("Lines have been changed outside of Metro since " + name + ".")
+	}
+	return nil
+}
+
+// Set the refs, HEAD and working directory of the repo to match a snapshot,
+// abandoning any ongoing absorb or replay.
+func restoreSnapshot(snapshot Snapshot, repo *git.Repository) error {
+	err := repo.StateCleanup()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(replayFile(repo))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	current, err := TakeSnapshot(repo)
+	if err != nil {
+		return err
+	}
+	for name := range current.Refs {
+		if _, ok := snapshot.Refs[name]; !ok {
+			ref, err := repo.References.Lookup(name)
+			if err != nil {
+				return err
+			}
+			err = ref.Delete()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for name, id := range snapshot.Refs {
+		if current.Refs[name] == id {
+			continue
+		}
+		oid, err := git.NewOid(id)
+		if err != nil {
+			return err
+		}
+		_, err = repo.References.Create(name, oid, true, "metro: restore from oplog")
+		if err != nil {
+			return err
+		}
+	}
+
+	if strings.HasPrefix(snapshot.Head, "refs/") {
+		err = repo.SetHead(snapshot.Head)
+		if err != nil {
+			return err
+		}
+	} else {
+		oid, err := git.NewOid(snapshot.Head)
+		if err != nil {
+			return err
+		}
+		err = repo.SetHeadDetached(oid)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Without a saved working directory, the best we can do is match the head commit.
+	var tree *git.Tree
+	if snapshot.WIP != "" {
+		oid, err := git.NewOid(snapshot.WIP)
+		if err != nil {
+			return err
+		}
+		tree, err = repo.LookupTree(oid)
+		if err != nil {
+			return err
+		}
+	} else {
+		head, err := GetCommit("HEAD", repo)
+		if err != nil {
+			return err
+		}
+		tree, err = head.Tree()
+		if err != nil {
+			return err
+		}
+	}
+
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	index.CleanupConflicts()
+	checkoutOps := git.CheckoutOpts{
+		Strategy: git.CheckoutForce | git.CheckoutRemoveUntracked,
+	}
+	err = repo.CheckoutTree(tree, &checkoutOps)
+	if err != nil {
+		return err
+	}
+	err = index.ReadTree(tree)
+	if err != nil {
+		return err
+	}
+	return index.Write()
+}
+
+func (s *Snapshot) equal(other *Snapshot) bool {
+	if s.Head != other.Head || s.WIP != other.WIP || len(s.Refs) != len(other.Refs) {
+		return false
+	}
+	for name, id := range s.Refs {
+		if other.Refs[name] != id {
+			return false
+		}
+	}
+	return true
+}
+
+func isJournaledRef(name string) bool {
+	if strings.HasPrefix(name, oplogRefPrefix) {
+		return false
+	}
+	for _, prefix := range journaledRefPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load the operations from the oplog, one per line, and the number of them currently applied.
+func loadOplog(repo *git.Repository) ([]Operation, int, error) {
+	dat, err := ioutil.ReadFile(oplogFile(repo))
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ops []Operation
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var op Operation
+		err = json.Unmarshal(scanner.Bytes(), &op)
+		if err != nil {
+			return nil, 0, errors.New("The oplog is corrupted: " + err.Error())
+		}
+		ops = append(ops, op)
+	}
+	if scanner.Err() != nil {
+		return nil, 0, scanner.Err()
+	}
+
+	position := len(ops)
+	dat, err = ioutil.ReadFile(oplogPositionFile(repo))
+	if err == nil {
+		position, err = strconv.Atoi(strings.TrimSpace(string(dat)))
+		if err != nil || position < 0 || position > len(ops) {
+			return nil, 0, errors.New("The oplog is corrupted: invalid position.")
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, 0, err
+	}
+	return ops, position, nil
+}
+
+func saveOplog(ops []Operation, position int, repo *git.Repository) error {
+	err := os.MkdirAll(metroDir(repo), 0755)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	for _, op := range ops {
+		dat, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		buffer.Write(dat)
+		buffer.WriteString("\n")
+	}
+	err = ioutil.WriteFile(oplogFile(repo), buffer.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(oplogPositionFile(repo), []byte(strconv.Itoa(position)+"\n"), 0644)
+}
@@ -0,0 +1,150 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run an operation the way journaled commands do, recording it in the oplog.
+func recordTestOperation(t *testing.T, repo *git.Repository, name string, operation func() error) {
+	before, err := TakeSnapshot(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = operation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RecordOperation(name, before, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	first, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var second *git.Commit
+	recordTestOperation(t, repo, "commit", func() error {
+		second = commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+		return nil
+	})
+	// Work in progress that isn't committed is restored too.
+	writeTestFiles(t, repo, map[string]string{"a.txt": "unfinished\n"})
+	recordTestOperation(t, repo, "delete commit", func() error {
+		return DeleteCommits(repo, 1, true)
+	})
+
+	head := func() string {
+		commit, err := GetCommit("HEAD", repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commit.Id().String()
+	}
+	if head() != first.Id().String() {
+		t.Fatal("DeleteCommits didn't delete the commit")
+	}
+
+	op, err := Undo(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Name != "delete commit" || head() != second.Id().String() {
+		t.Errorf("Undo undid %q to %s, want delete commit back to %s", op.Name, head(), second.Id())
+	}
+	dat, err := ioutil.ReadFile(filepath.Join(repo.Workdir(), "a.txt"))
+	if err != nil || string(dat) != "unfinished\n" {
+		t.Errorf("Undo restored a.txt as %q, %v, want the work in progress", dat, err)
+	}
+
+	// Undoing further is refused until the work in progress is dealt with.
+	_, err = Undo(repo)
+	if err == nil {
+		t.Fatal("Undo with changes made since the operation succeeded, want error")
+	}
+	writeTestFiles(t, repo, map[string]string{"a.txt": "a\n"})
+	_, err = Undo(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head() != first.Id().String() {
+		t.Error("Undoing the commit didn't remove it")
+	}
+	_, err = Undo(repo)
+	if err == nil {
+		t.Error("Undo with nothing left to undo succeeded, want error")
+	}
+
+	_, err = Redo(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, repo, map[string]string{"a.txt": "unfinished\n"})
+	_, err = Redo(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head() != first.Id().String() {
+		t.Error("Redoing both operations didn't delete the commit again")
+	}
+	_, err = Redo(repo)
+	if err == nil {
+		t.Error("Redo with nothing left to redo succeeded, want error")
+	}
+}
+
+func TestOplogKeepsObjectsReachable(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	deleted := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	writeTestFiles(t, repo, map[string]string{"b.txt": "unfinished\n"})
+	var wip string
+	recordTestOperation(t, repo, "delete commit", func() error {
+		snapshot, err := TakeSnapshot(repo)
+		if err != nil {
+			return err
+		}
+		wip = snapshot.WIP
+		return DeleteCommits(repo, 1, true)
+	})
+
+	kept, err := refsWithPrefix(oplogRefPrefix, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := kept[deleted.Id().String()]; !ok || !id.Equal(deleted.Id()) {
+		t.Errorf("The deleted commit isn't kept by a ref, kept %v", kept)
+	}
+	id, ok := kept[wip]
+	if !ok {
+		t.Fatalf("The working directory isn't kept by a ref, kept %v", kept)
+	}
+	commit, err := repo.LookupCommit(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.TreeId().String() != wip {
+		t.Error("The working directory is kept by a commit of another tree")
+	}
+
+	// Refs kept for the oplog aren't part of the repo's state.
+	snapshot, err := TakeSnapshot(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range snapshot.Refs {
+		if strings.HasPrefix(name, oplogRefPrefix) {
+			t.Errorf("Snapshot includes %s", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// Config keys for protecting lines from having their history changed.
+const (
+	// Comma separated names of the protected lines.
+	protectedLinesKey = "metro.protectedLines"
+	// If true, normal commits can be made on protected lines, otherwise only absorbs can.
+	protectedAllowCommitKey = "metro.protectedAllowCommit"
+)
+
+// Returns the names of the lines protected in the repo's config.
+func ProtectedLines(repo *git.Repository) ([]string, error) {
+	value, err := configString(protectedLinesKey, repo)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Returns true if the given line is protected.
+func IsProtected(name string, repo *git.Repository) (bool, error) {
+	names, err := ProtectedLines(repo)
+	if err != nil {
+		return false, err
+	}
+	for _, protected := range names {
+		if protected == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Raises an error if the given line is protected, so its history can't be changed or the line deleted.
+func assertUnprotected(name string, repo *git.Repository) error {
+	protected, err := IsProtected(name, repo)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return errors.New("Line " + name + " is protected, its history can't be changed.")
+	}
+	return nil
+}
+
+// Raises an error if setting the refs of the repo from one snapshot to another would change the history of a
+// protected line, as undoing or redoing an operation can. Protected lines may only be created or moved forward.
+// action - Description of the change for the error, e.g. "undoing commit"
+func assertRestoreKeepsProtected(from Snapshot, to Snapshot, action string, repo *git.Repository) error {
+	names, err := ProtectedLines(repo)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		ref := "refs/heads/" + name
+		fromID, existed := from.Refs[ref]
+		toID := to.Refs[ref]
+		if !existed || fromID == toID {
+			continue
+		}
+		if toID != "" {
+			fromOid, err := git.NewOid(fromID)
+			if err != nil {
+				return err
+			}
+			toOid, err := git.NewOid(toID)
+			if err != nil {
+				return err
+			}
+			forward, err := repo.DescendantOf(toOid, fromOid)
+			if err != nil {
+				return err
+			}
+			if forward {
+				continue
+			}
+		}
+		return errors.New("Line " + name + " is protected, " + action + " would change its history.")
+	}
+	return nil
+}
+
+// Raises an error if the current line is protected, so its history can't be changed.
+func assertCanRewrite(repo *git.Repository) error {
+	name, err := currentLine(repo)
+	if err != nil {
+		return err
+	}
+	return assertUnprotected(name, repo)
+}
+
+// Raises an error if the given line is protected and only accepts absorbs.
+func assertLineCanCommit(name string, repo *git.Repository) error {
+	protected, err := IsProtected(name, repo)
+	if err != nil {
+		return err
+	}
+	if !protected {
+		return nil
+	}
+	allowed, err := configBool(protectedAllowCommitKey, repo)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("Line " + name + " is protected, it can only be changed by absorbing another line.")
+	}
+	return nil
+}
+
+// Raises an error if the current line is protected and only accepts absorbs.
+func assertCanCommit(repo *git.Repository) error {
+	name, err := currentLine(repo)
+	if err != nil {
+		return err
+	}
+	return assertLineCanCommit(name, repo)
+}
+
+// Returns the line HEAD is on, or whose WIP HEAD was left on, so that the line itself is checked for protection.
+func currentLine(repo *git.Repository) (string, error) {
+	head, err := GetHead(repo)
+	if err != nil {
+		return "", err
+	}
+	if head.Line == "" {
+		return "", head.notOnLineError()
+	}
+	return head.Line, nil
+}
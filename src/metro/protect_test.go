@@ -0,0 +1,93 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"strings"
+	"testing"
+)
+
+// Protect the given lines in the repo's config.
+func protectTestLines(t *testing.T, repo *git.Repository, names ...string) {
+	err := setConfigString(protectedLinesKey, strings.Join(names, ","), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProtectedLineRefusesRewrites(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("release", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := commitTestFiles(t, repo, "Work on "+main, map[string]string{"a.txt": "a\n"})
+	protectTestLines(t, repo, main, "release")
+
+	writeTestFiles(t, repo, map[string]string{"a.txt": "changed\n"})
+	refusals := map[string]error{
+		"DeleteCommits": DeleteCommits(repo, 1, false),
+		"Patch":         Patch(repo, "Patched"),
+		"DeleteBranch":  DeleteBranch("release", repo),
+		"RenameBranch":  RenameBranch("release", "renamed", repo),
+	}
+	_, refusals["PatchCommit"] = PatchCommit(repo, "HEAD", "Patched")
+	for name, err := range refusals {
+		if err == nil || !strings.Contains(err.Error(), "protected") {
+			t.Errorf("%s on a protected line = %v, want a protected line error", name, err)
+		}
+	}
+
+	current, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Id().Equal(head.Id()) {
+		t.Error("A refused rewrite changed the protected line")
+	}
+	if !LineExists("release", repo) || LineExists("renamed", repo) {
+		t.Error("A refused delete or rename changed the release line")
+	}
+}
+
+func TestUndoKeepsProtectedLines(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := TakeSnapshot(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on "+main, map[string]string{"a.txt": "a\n"})
+	err = RecordOperation("commit", before, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protectTestLines(t, repo, main)
+
+	// Undoing the commit would take the protected line backwards.
+	_, err = Undo(repo)
+	if err == nil || !strings.Contains(err.Error(), "protected") {
+		t.Fatalf("Undo on a protected line = %v, want a protected line error", err)
+	}
+
+	// Moving it forwards again is allowed.
+	protectTestLines(t, repo)
+	_, err = Undo(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protectTestLines(t, repo, main)
+	_, err = Redo(repo)
+	if err != nil {
+		t.Errorf("Redo moving a protected line forward = %v", err)
+	}
+}
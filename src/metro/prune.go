@@ -0,0 +1,171 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"time"
+)
+
+// Archived lines are kept under this namespace as refs/metro/archive/<line name>, and never expire.
+const archivePrefix = "refs/metro/archive/"
+
+// A line that can be pruned, and why.
+type PruneCandidate struct {
+	Name string
+	// The line it has been fully absorbed into, or "" if it is only stale.
+	AbsorbedInto string
+	// When the last commit on the line was made.
+	LastCommit time.Time
+}
+
+// Finds lines that have been fully absorbed into the target line, or that have had no commits for staleDays days.
+// A line only counts as absorbed if it has commits of its own that the target absorbed, so lines that were
+// created but never committed to aren't pruned. The target line, the current line, the trunk line, lines that
+// other lines grew from, protected lines and lines with work in progress are never pruned.
+// target - Line to check for absorbed lines, or "" to not check
+// staleDays - Days without commits after which a line is stale, or 0 to not check
+func PruneCandidates(target string, staleDays int, repo *git.Repository) ([]PruneCandidate, error) {
+	if target == "" && staleDays <= 0 {
+		return nil, errors.New("Nothing to prune by, give a line the lines were absorbed into or a number of days.")
+	}
+	var targetCommit *git.Commit
+	if target != "" {
+		if !LineExists(target, repo) {
+			return nil, noLineError("No line called "+target+".", target, repo)
+		}
+		var err error
+		targetCommit, err = GetCommit(target, repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	head, err := GetHead(repo)
+	if err != nil {
+		return nil, err
+	}
+	current := head.Line
+	trunk, err := trunkLine(repo)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := parentLines(repo)
+	if err != nil {
+		return nil, err
+	}
+	names, err := Lines(repo)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().AddDate(0, 0, -staleDays)
+
+	var candidates []PruneCandidate
+	for _, name := range names {
+		if name == current || name == target || name == trunk || parents[name] || LineExists(name+WipString, repo) {
+			continue
+		}
+		protected, err := IsProtected(name, repo)
+		if err != nil {
+			return nil, err
+		}
+		if protected {
+			continue
+		}
+		tip, err := GetCommit(name, repo)
+		if err != nil {
+			return nil, err
+		}
+
+		candidate := PruneCandidate{Name: name, LastCommit: tip.Committer().When}
+		absorbed := false
+		if targetCommit != nil {
+			absorbed, err = isAbsorbed(tip, targetCommit, repo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if absorbed {
+			candidate.AbsorbedInto = target
+		}
+		if absorbed || (staleDays > 0 && candidate.LastCommit.Before(cutoff)) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates, nil
+}
+
+// Returns true if the target has absorbed the given line tip, along with commits of the line's own.
+// A tip on the target's own history, reached by following first parents, was never absorbed:
+// the line was created there and has no commits the target doesn't.
+func isAbsorbed(tip *git.Commit, target *git.Commit, repo *git.Repository) (bool, error) {
+	contained, err := repo.DescendantOf(target.Id(), tip.Id())
+	if err != nil || !contained {
+		return false, err
+	}
+	for commit := target; commit != nil; commit = commit.Parent(0) {
+		if commit.Id().Equal(tip.Id()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Moves a line and its WIP, if any, to the archive, from where they can be restored at any time.
+func ArchiveBranch(name string, repo *git.Repository) error {
+	err := assertUnprotected(name, repo)
+	if err != nil {
+		return err
+	}
+	names := []string{name}
+	if CommitExists(name+WipString, repo) {
+		names = append(names, name+WipString)
+	}
+	for _, n := range names {
+		if _, err := repo.References.Lookup(archivePrefix + n); err == nil {
+			return errors.New("A line called " + n + " is already archived.")
+		}
+	}
+
+	for _, n := range names {
+		branch, err := repo.LookupBranch(n, git.BranchLocal)
+		if err != nil {
+			return err
+		}
+		_, err = repo.References.Create(archivePrefix+n, branch.Target(), false, "metro: archive line "+n)
+		if err != nil {
+			return err
+		}
+		err = branch.Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Brings back an archived line and its WIP, if any.
+func restoreArchived(name string, repo *git.Repository) error {
+	ref, err := repo.References.Lookup(archivePrefix + name)
+	if err != nil {
+		return errors.New("No deleted or archived line called " + name + ".")
+	}
+	refs := []*git.Reference{ref}
+	if wip, err := repo.References.Lookup(archivePrefix + name + WipString); err == nil {
+		refs = append(refs, wip)
+	}
+
+	for _, r := range refs {
+		commit, err := repo.LookupCommit(r.Target())
+		if err != nil {
+			return err
+		}
+		_, err = repo.CreateBranch(r.Name()[len(archivePrefix):], commit, false)
+		if err != nil {
+			return err
+		}
+		err = r.Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
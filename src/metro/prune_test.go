@@ -0,0 +1,139 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"testing"
+	"time"
+)
+
+func TestPruneCandidatesAbsorbed(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines with commits of their own, one of which also has work in progress.
+	for _, line := range []string{"absorbed", "parked", "open"} {
+		_, err = CreateBranch(line, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = SwitchBranch(line, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitTestFiles(t, repo, "Work on "+line, map[string]string{line + ".txt": line + "\n"})
+		if line == "parked" {
+			writeTestFiles(t, repo, map[string]string{line + ".txt": "unfinished\n"})
+		}
+		err = SwitchBranch(main, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A line that was created but never committed to.
+	_, err = CreateBranch("fresh", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitTestFiles(t, repo, "Work on "+main, map[string]string{"main.txt": "main\n"})
+	for _, line := range []string{"absorbed", "parked"} {
+		conflicts, err := Absorb(line, repo)
+		if err != nil || conflicts {
+			t.Fatalf("Absorb(%s) = %v, %v", line, conflicts, err)
+		}
+	}
+
+	candidates, err := PruneCandidates(main, 0, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 1 || candidates[0].Name != "absorbed" || candidates[0].AbsorbedInto != main {
+		t.Errorf("PruneCandidates = %+v, want only absorbed, absorbed into %s", candidates, main)
+	}
+}
+
+func TestPruneCandidatesStale(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	_, err := CreateBranch("recent", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every line was committed to just now, so none has gone a day without commits.
+	candidates, err := PruneCandidates("", 1, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("PruneCandidates = %+v, want none", candidates)
+	}
+
+	_, err = PruneCandidates("", 0, repo)
+	if err == nil {
+		t.Error("PruneCandidates with nothing to prune by succeeded, want error")
+	}
+}
+
+func TestPruneCandidatesKeepsTrunkAndParents(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = setConfigString("init.defaultBranch", main, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit to the trunk a month ago, and start quiet lines from there.
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := head.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &git.Signature{Name: "Test User", Email: "test@email.com", When: time.Now().AddDate(0, -1, 0)}
+	_, err = repo.CreateCommit("HEAD", old, old, "Old work", tree, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"quiet", "base"} {
+		_, err = CreateBranch(line, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = CreateBranchFrom("child", "base", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SwitchBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on feature", map[string]string{"feature.txt": "feature\n"})
+
+	candidates, err := PruneCandidates("", 7, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, candidate.Name)
+	}
+	if len(names) != 2 || names[0] != "child" || names[1] != "quiet" {
+		t.Errorf("PruneCandidates = %v, want child and quiet but not %s or base", names, main)
+	}
+}
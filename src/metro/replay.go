@@ -0,0 +1,315 @@
+package metro
+
+import (
+	"encoding/json"
+	"errors"
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// A commit to be recreated on top of the current head while replaying.
+type replayStep struct {
+	// The commit to recreate.
+	Id string
+	// The message of the new commit.
+	Message string
+	// If true the commit is combined with the one replayed before it, rather than recreated on its own.
+	Squash bool
+}
+
+// The progress of a replay, saved so that it can be continued after conflicts are resolved.
+type replayState struct {
+	// The name of the operation doing the replay, shown to the user.
+	Operation string
+	// The head before the operation started.
+	OrigHead string
+	// The tree of the working directory before the operation started,
+	// if the operation moved uncommitted work into a commit.
+	OrigWork string
+	// The step that stopped with conflicts, if any.
+	Current *replayStep
+	// The steps still to be replayed.
+	Steps []replayStep
+}
+
+// The directory in which Metro keeps its own state, inside the .git directory.
+func metroDir(repo *git.Repository) string {
+	return filepath.Join(repo.Path(), "metro")
+}
+
+func replayFile(repo *git.Repository) string {
+	return filepath.Join(metroDir(repo), "replay")
+}
+
+// Returns true if commits are being replayed and are waiting for conflicts to be resolved.
+func ReplayOngoing(repo *git.Repository) bool {
+	_, err := os.Stat(replayFile(repo))
+	return err == nil
+}
+
+// Returns the name of the operation that is waiting for conflicts to be resolved.
+func ReplayOperation(repo *git.Repository) (string, error) {
+	state, err := loadReplay(repo)
+	if err != nil {
+		return "", err
+	}
+	return state.Operation, nil
+}
+
+// Recreate the given commits, oldest first, on top of the current head.
+// If a commit can't be replayed without conflicts the replay stops, leaving the conflicts in the
+// working directory to be resolved, and returns true. ContinueReplay finishes the replay.
+// operation - The name of the operation doing the replay, shown to the user
+// origHead - The head before the operation started
+// origWork - The tree of the working directory before the operation started, or nil if it was unchanged
+func replay(operation string, origHead *git.Oid, origWork *git.Oid, steps []replayStep, repo *git.Repository) (bool, error) {
+	state := replayState{Operation: operation, OrigHead: origHead.String(), Steps: steps}
+	if origWork != nil {
+		state.OrigWork = origWork.String()
+	}
+	return runReplay(&state, repo)
+}
+
+// The steps to replay the given commits unchanged.
+func pickSteps(commits []*git.Commit) []replayStep {
+	var steps []replayStep
+	for _, commit := range commits {
+		steps = append(steps, replayStep{Id: commit.Id().String(), Message: commit.Message()})
+	}
+	return steps
+}
+
+// Stop a replay that is waiting for conflicts to be resolved,
+// and put the line and working directory back as they were before the operation started.
+func AbortReplay(repo *git.Repository) error {
+	state, err := loadReplay(repo)
+	if err != nil {
+		return err
+	}
+
+	err = repo.StateCleanup()
+	if err != nil {
+		return err
+	}
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	index.CleanupConflicts()
+
+	origHead, err := GetCommit(state.OrigHead, repo)
+	if err != nil {
+		return err
+	}
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	err = repo.ResetToCommit(origHead, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return err
+	}
+
+	// Bring back work that the operation had moved into a commit.
+	if state.OrigWork != "" {
+		oid, err := git.NewOid(state.OrigWork)
+		if err != nil {
+			return err
+		}
+		tree, err := repo.LookupTree(oid)
+		if err != nil {
+			return err
+		}
+		err = repo.CheckoutTree(tree, &checkoutOps)
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(replayFile(repo))
+}
+
+// Commit the resolved conflicts of the replay step that stopped, then replay the remaining commits.
+// If message is empty the message of the replayed commit is used.
+// Returns true if another commit stopped with conflicts.
+func ContinueReplay(repo *git.Repository, message string) (bool, error) {
+	state, err := loadReplay(repo)
+	if err != nil {
+		return false, err
+	}
+
+	if state.Current != nil {
+		if message != "" {
+			state.Current.Message = message
+		}
+
+		// Remove index conflicts, the resolved files are staged instead.
+		index, err := repo.Index()
+		if err != nil {
+			return false, err
+		}
+		index.CleanupConflicts()
+		tree, err := stageAll(repo)
+		if err != nil {
+			return false, err
+		}
+
+		err = commitStep(*state.Current, tree, repo)
+		if err != nil {
+			return false, err
+		}
+		state.Current = nil
+	}
+
+	return runReplay(state, repo)
+}
+
+// Replay the remaining steps of a replay, saving the state if one stops with conflicts.
+func runReplay(state *replayState, repo *git.Repository) (bool, error) {
+	for len(state.Steps) > 0 {
+		step := state.Steps[0]
+		state.Steps = state.Steps[1:]
+
+		conflicts, err := applyStep(step, repo)
+		if err != nil {
+			return false, err
+		}
+		if conflicts {
+			state.Current = &step
+			return true, saveReplay(state, repo)
+		}
+	}
+
+	err := os.Remove(replayFile(repo))
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// Cherry-pick the step's commit onto the head, and commit it if there were no conflicts.
+// Returns true if there were conflicts.
+func applyStep(step replayStep, repo *git.Repository) (bool, error) {
+	commit, err := GetCommit(step.Id, repo)
+	if err != nil {
+		return false, err
+	}
+
+	// A commit that would be recreated exactly as it is can be reused.
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	if !step.Squash && commit.ParentCount() > 0 && commit.ParentId(0).Equal(head.Id()) && step.Message == commit.Message() {
+		checkoutOps := git.CheckoutOpts{}
+		checkoutOps.Strategy = git.CheckoutForce
+		return false, repo.ResetToCommit(commit, git.ResetHard, &checkoutOps)
+	}
+
+	opts, err := git.DefaultCherrypickOptions()
+	if err != nil {
+		return false, err
+	}
+	// Absorb commits are replayed relative to the line they were made on.
+	if commit.ParentCount() > 1 {
+		opts.Mainline = 1
+	}
+	opts.CheckoutOpts = git.CheckoutOpts{
+		Strategy: git.CheckoutForce | git.CheckoutAllowConflicts,
+	}
+	err = repo.Cherrypick(commit, opts)
+	if err != nil {
+		return false, err
+	}
+
+	index, err := repo.Index()
+	if err != nil {
+		return false, err
+	}
+	if index.HasConflicts() {
+		return true, nil
+	}
+
+	oid, err := index.WriteTree()
+	if err != nil {
+		return false, err
+	}
+	tree, err := repo.LookupTree(oid)
+	if err != nil {
+		return false, err
+	}
+	return false, commitStep(step, tree, repo)
+}
+
+// Commit the replayed tree of a step on top of the head, keeping the author of the original commit,
+// and clear the cherry-pick state. A squash step replaces the head instead, combining their messages.
+func commitStep(step replayStep, tree *git.Tree, repo *git.Repository) error {
+	commit, err := GetCommit(step.Id, repo)
+	if err != nil {
+		return err
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return err
+	}
+	committer, err := userSignature(repo)
+	if err != nil {
+		return err
+	}
+
+	author := commit.Author()
+	message := step.Message
+	// An absorb commit stays an absorb of the same commits.
+	parents := []*git.Commit{head}
+	for i := uint(1); i < commit.ParentCount(); i++ {
+		parents = append(parents, commit.Parent(i))
+	}
+	if step.Squash {
+		author = head.Author()
+		message = combineMessages([]string{head.Message(), step.Message})
+		parents = nil
+		for i := uint(0); i < head.ParentCount(); i++ {
+			parents = append(parents, head.Parent(i))
+		}
+		// The head is replaced, so move back to its parent first.
+		err = repo.ResetToCommit(parents[0], git.ResetSoft, &git.CheckoutOpts{})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = repo.CreateCommit("HEAD", author, committer, message, tree, parents...)
+	if err != nil {
+		return err
+	}
+	return repo.StateCleanup()
+}
+
+func loadReplay(repo *git.Repository) (*replayState, error) {
+	dat, err := ioutil.ReadFile(replayFile(repo))
+	if os.IsNotExist(err) {
+		return nil, errors.New("There are no commits waiting to be replayed.")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state replayState
+	err = json.Unmarshal(dat, &state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func saveReplay(state *replayState, repo *git.Repository) error {
+	err := os.MkdirAll(metroDir(repo), 0755)
+	if err != nil {
+		return err
+	}
+	dat, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(replayFile(repo), dat, 0644)
+}
@@ -0,0 +1,103 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Create a repo in a temporary directory for a test, with Metro's initial commit made by a placeholder user.
+// Remove it with removeTestRepo once the test is done.
+func newTestRepo(t *testing.T) *git.Repository {
+	dir, err := ioutil.TempDir("", "metro-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := git.InitRepository(dir+"/.git", false)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{"user.name": "Test User", "user.email": "test@email.com"} {
+		err = setConfigString(key, value, repo)
+		if err != nil {
+			removeTestRepo(repo)
+			t.Fatal(err)
+		}
+	}
+	err = Commit(repo, "Create repository")
+	if err != nil {
+		removeTestRepo(repo)
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func removeTestRepo(repo *git.Repository) {
+	dir := repo.Workdir()
+	repo.Free()
+	os.RemoveAll(dir)
+}
+
+// Write files into the working directory, by path relative to it.
+func writeTestFiles(t *testing.T, repo *git.Repository, files map[string]string) {
+	for path, contents := range files {
+		file := filepath.Join(repo.Workdir(), path)
+		err := os.MkdirAll(filepath.Dir(file), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(file, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Write files and commit them to the current line, returning the new commit.
+func commitTestFiles(t *testing.T, repo *git.Repository, message string, files map[string]string) *git.Commit {
+	writeTestFiles(t, repo, files)
+	_, err := CommitChanges(repo, message, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+// Returns the contents of a file in a commit, or "" if the commit doesn't have it.
+func testFileAt(t *testing.T, commit *git.Commit, path string, repo *git.Repository) string {
+	tree, err := commit.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := tree.EntryByPath(path)
+	if err != nil {
+		return ""
+	}
+	blob, err := repo.LookupBlob(entry.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(blob.Contents())
+}
+
+// Returns the numbered lines "1\n" to "n\n", with the given lines replaced, by line number.
+func numberedLines(n int, replace map[int]string) string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		line, ok := replace[i]
+		if !ok {
+			line = strconv.Itoa(i)
+		}
+		lines = append(lines, line+"\n")
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,69 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strconv"
+)
+
+// Adds a commit to the current line undoing the changes of the given commit, leaving history intact.
+// For an absorb commit, mainline is the parent whose contents are restored, counting from 1 for the line
+// the absorb was made on. Other commits only have a mainline of 1.
+// Returns true if undoing the changes conflicts with later commits, ContinueReplay then finishes it.
+func Revert(revision string, mainline int, message string, repo *git.Repository) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertCanCommit(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertNoChanges(repo)
+	if err != nil {
+		return false, err
+	}
+
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return false, err
+	}
+	if commit.ParentCount() == 0 {
+		return false, errors.New("The first commit of a line can't be reverted.")
+	}
+	if mainline < 1 || uint(mainline) > commit.ParentCount() {
+		if commit.ParentCount() == 1 {
+			return false, errors.New("Commit " + ShortID(commit) + " isn't an absorb, so it only has mainline 1.")
+		}
+		return false, errors.New("Commit " + ShortID(commit) + " has mainlines 1 to " + strconv.Itoa(int(commit.ParentCount())) + ".")
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	if message == "" {
+		message = RevertMessage(commit)
+	}
+
+	// The inverse of the commit is made as a commit going from it back to its mainline parent,
+	// which can then be replayed onto the head like any other commit.
+	tree, err := commit.Parent(uint(mainline - 1)).Tree()
+	if err != nil {
+		return false, err
+	}
+	signature, err := userSignature(repo)
+	if err != nil {
+		return false, err
+	}
+	inverseID, err := repo.CreateCommit("", signature, signature, message, tree, commit)
+	if err != nil {
+		return false, err
+	}
+
+	return replay("revert", head.Id(), nil, []replayStep{{Id: inverseID.String(), Message: message}}, repo)
+}
+
+// The default message of a commit reverting the given commit.
+func RevertMessage(commit *git.Commit) string {
+	return "Revert \"" + commit.Summary() + "\"\n\nThis reverts commit " + commit.Id().String() + ".\n"
+}
@@ -0,0 +1,59 @@
+package metro
+
+import (
+	"testing"
+)
+
+func TestRevert(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	a := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+
+	if _, err := Revert(ShortID(a), 2, "", repo); err == nil {
+		t.Error("Revert of a commit with a single parent at mainline 2 succeeded, want error")
+	}
+	conflicts, err := Revert(ShortID(a), 1, "", repo)
+	if err != nil || conflicts {
+		t.Fatalf("Revert = %v, %v", conflicts, err)
+	}
+	reverted, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Summary() != "Revert \"Add a\"" || reverted.ParentCount() != 1 {
+		t.Errorf("Revert made %q, want a commit reverting Add a", reverted.Summary())
+	}
+	if testFileAt(t, reverted, "a.txt", repo) != "" || testFileAt(t, reverted, "b.txt", repo) != "b\n" {
+		t.Error("Revert didn't undo only the reverted commit's changes")
+	}
+}
+
+func TestRevertAbsorb(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	absorb := absorbTestLine(t, repo, "feature")
+	current, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Revert(ShortID(absorb), 3, "", repo); err == nil {
+		t.Error("Revert of an absorb at mainline 3 succeeded, want error")
+	}
+	// Mainline 1 undoes what the absorbed line brought in.
+	conflicts, err := Revert(ShortID(absorb), 1, "Back out feature", repo)
+	if err != nil || conflicts {
+		t.Fatalf("Revert = %v, %v", conflicts, err)
+	}
+	reverted, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted.Summary() != "Back out feature" || reverted.ParentCount() != 1 {
+		t.Errorf("Revert made %q with %d parents", reverted.Summary(), reverted.ParentCount())
+	}
+	if testFileAt(t, reverted, "feature.txt", repo) != "" || testFileAt(t, reverted, current+".txt", repo) == "" {
+		t.Error("Reverting mainline 1 didn't undo only the absorbed line's changes")
+	}
+}
@@ -0,0 +1,186 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strconv"
+	"strings"
+)
+
+// What a rewrite plan does with a commit.
+const (
+	// Keep the commit as it is.
+	PickAction = "pick"
+	// Keep the commit with a new message.
+	RewordAction = "reword"
+	// Combine the commit with the one before it in the plan.
+	SquashAction = "squash"
+	// Remove the commit and its changes.
+	DropAction = "drop"
+)
+
+// A line of a rewrite plan.
+type RewriteStep struct {
+	Action string
+	Commit *git.Commit
+	// The new message of a reworded commit.
+	Message string
+}
+
+// Parse a rewrite plan, one commit per line, in the order they should end up on the line.
+// Each line is an action, a commit and, for reword, the new message.
+// Anything after the commit on other lines is ignored, so the commit's summary can be kept there.
+// Blank lines and lines starting with # are ignored.
+func ParseRewritePlan(plan string, repo *git.Repository) ([]RewriteStep, error) {
+	var steps []RewriteStep
+	for i, line := range strings.Split(plan, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineNum := strconv.Itoa(i + 1)
+
+		// Actions and commits may be separated by any spaces or tabs.
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, errors.New("Line " + lineNum + " of the plan needs an action and a commit.")
+		}
+		action := fields[0]
+		if action != PickAction && action != RewordAction && action != SquashAction && action != DropAction {
+			return nil, errors.New("Unknown action on line " + lineNum + " of the plan: " + action)
+		}
+		commit, err := GetCommit(fields[1], repo)
+		if err != nil {
+			return nil, errors.New("Unknown commit on line " + lineNum + " of the plan: " + fields[1])
+		}
+
+		step := RewriteStep{Action: action, Commit: commit}
+		if action == RewordAction {
+			// The message is the rest of the line, with its own spacing kept.
+			rest := strings.TrimSpace(strings.TrimSpace(line[len(action):])[len(fields[1]):])
+			if rest == "" {
+				return nil, errors.New("Line " + lineNum + " of the plan rewords a commit without giving a message.")
+			}
+			step.Message = rest
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return nil, errors.New("The plan is empty.")
+	}
+	return steps, nil
+}
+
+// Returns a plan that keeps the given number of commits at the head of the current line as they are,
+// to be edited into a rewrite plan.
+func RewritePlan(commitsBack int, repo *git.Repository) (string, error) {
+	commits, err := LineHistory("HEAD", commitsBack, repo)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) < commitsBack {
+		return "", errors.New("The line only has " + strconv.Itoa(len(commits)) + " commits.")
+	}
+
+	var plan strings.Builder
+	for i := len(commits) - 1; i >= 0; i-- {
+		plan.WriteString(PickAction + " " + ShortID(commits[i]) + " " + commits[i].Summary() + "\n")
+	}
+	plan.WriteString("\n")
+	plan.WriteString("# Commits are listed oldest first, reorder the lines to reorder the commits.\n")
+	plan.WriteString("# pick <commit>            keep the commit\n")
+	plan.WriteString("# reword <commit> <message> keep the commit with a new message\n")
+	plan.WriteString("# squash <commit>          combine the commit with the one above it\n")
+	plan.WriteString("# drop <commit>            remove the commit and its changes\n")
+	return plan.String(), nil
+}
+
+// Rewrite the commits at the head of the current line according to a plan.
+// The plan must list every commit after the oldest one it mentions, each exactly once.
+// If a commit can't be replayed without conflicts the rewrite stops and returns true,
+// ContinueReplay or AbortReplay then finishes it.
+func Rewrite(steps []RewriteStep, repo *git.Repository) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertNoChanges(repo)
+	if err != nil {
+		return false, err
+	}
+
+	listed := map[string]bool{}
+	for _, step := range steps {
+		id := step.Commit.Id().String()
+		if listed[id] {
+			return false, errors.New("Commit " + ShortID(step.Commit) + " is listed more than once in the plan.")
+		}
+		listed[id] = true
+	}
+
+	// Walk back along the line until every listed commit has been found.
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	base := head
+	for found := 0; found < len(listed); found++ {
+		if base == nil {
+			break
+		}
+		if !listed[base.Id().String()] {
+			return false, errors.New("Commit " + ShortID(base) + " is missing from the plan, list it with drop to remove it.")
+		}
+		base = base.Parent(0)
+	}
+	if base == nil {
+		for _, step := range steps {
+			if step.Commit.ParentCount() == 0 {
+				return false, errors.New("The first commit of a line can't be rewritten.")
+			}
+		}
+		return false, errors.New("The plan lists commits that aren't on the current line.")
+	}
+
+	var replaySteps []replayStep
+	for _, step := range steps {
+		switch step.Action {
+		case DropAction:
+			continue
+		case SquashAction:
+			if len(replaySteps) == 0 {
+				return false, errors.New("Commit " + ShortID(step.Commit) + " has nothing before it to be squashed into.")
+			}
+			if step.Commit.ParentCount() > 1 {
+				return false, errors.New("Commit " + ShortID(step.Commit) + " is an absorb and can't be squashed.")
+			}
+		case RewordAction:
+			err = CheckMessage(step.Message, repo)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		message := step.Message
+		if message == "" {
+			message = step.Commit.Message()
+		}
+		replaySteps = append(replaySteps, replayStep{
+			Id:      step.Commit.Id().String(),
+			Message: message,
+			Squash:  step.Action == SquashAction,
+		})
+	}
+
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	err = repo.ResetToCommit(base, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return false, err
+	}
+	return replay("rewrite", head.Id(), nil, replaySteps, repo)
+}
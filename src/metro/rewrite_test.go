@@ -0,0 +1,72 @@
+package metro
+
+import (
+	"testing"
+)
+
+func TestParseRewritePlan(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	a := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	b := commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+
+	// Fields may be separated by several spaces or tabs, and the message keeps its own spacing.
+	plan := "# Comment\n\npick  " + ShortID(a) + " Add a\n\treword\t" + ShortID(b) + "\t  Add  b, better \n"
+	steps, err := ParseRewritePlan(plan, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("ParseRewritePlan = %+v, want 2 steps", steps)
+	}
+	if steps[0].Action != PickAction || !steps[0].Commit.Id().Equal(a.Id()) || steps[0].Message != "" {
+		t.Errorf("First step = %+v, want pick of %s", steps[0], ShortID(a))
+	}
+	if steps[1].Action != RewordAction || !steps[1].Commit.Id().Equal(b.Id()) || steps[1].Message != "Add  b, better" {
+		t.Errorf("Second step = %+v, want reword of %s to %q", steps[1], ShortID(b), "Add  b, better")
+	}
+
+	for _, bad := range []string{"", "pick", "pick nothing", "edit " + ShortID(a), "reword " + ShortID(a) + " \t "} {
+		if _, err := ParseRewritePlan(bad, repo); err == nil {
+			t.Errorf("ParseRewritePlan(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	a := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	b := commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+	c := commitTestFiles(t, repo, "Add c", map[string]string{"c.txt": "c\n"})
+
+	// Move c before a, reword it and drop b.
+	steps, err := ParseRewritePlan("reword "+ShortID(c)+" Add c first\npick "+ShortID(a)+"\ndrop "+ShortID(b), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := Rewrite(steps, repo)
+	if err != nil || conflicts {
+		t.Fatalf("Rewrite = %v, %v", conflicts, err)
+	}
+
+	commits, err := LineHistory("HEAD", 3, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commits[0].Summary() != "Add a" || commits[1].Summary() != "Add c first" || commits[2].Summary() != "Create repository" {
+		t.Errorf("Rewrite left %q, %q, %q", commits[0].Summary(), commits[1].Summary(), commits[2].Summary())
+	}
+	if testFileAt(t, commits[0], "b.txt", repo) != "" || testFileAt(t, commits[0], "c.txt", repo) != "c\n" {
+		t.Error("Rewrite kept the dropped commit's changes or lost the reworded commit's")
+	}
+
+	// Every commit after the oldest listed one must be in the plan.
+	steps, err = ParseRewritePlan("pick "+ShortID(commits[1]), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rewrite(steps, repo); err == nil {
+		t.Error("Rewrite with a commit missing from the plan succeeded, want error")
+	}
+}
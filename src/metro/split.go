@@ -0,0 +1,362 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A file changed by a commit, with the hunks of the change.
+type SplitFile struct {
+	Path string
+	// The header of each hunk, as shown in a diff.
+	Hunks []string
+
+	delta git.DiffDelta
+	hunks []splitHunk
+}
+
+type splitHunk struct {
+	git.DiffHunk
+	lines []git.DiffLine
+}
+
+// Returns the files changed by a commit and their hunks, which can be used to choose how to split it.
+func SplitCandidates(revision string, repo *git.Repository) ([]SplitFile, error) {
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return nil, err
+	}
+	if commit.ParentCount() != 1 {
+		return nil, errors.New("Only commits with a single parent can be split.")
+	}
+	return commitHunks(commit.Parent(0), commit, repo)
+}
+
+// Splits a commit on the current line into several commits, one for each group of changes.
+// Each group lists the paths whose changes go into its commit; a path may be a file, a directory,
+// or "file:n" to take only the nth hunk of a file. Any changes not in a group go into a final commit.
+// The commits after the split commit are replayed on top of the new commits.
+// Returns true if replaying caused conflicts, which must be resolved before the replay can be continued.
+func Split(repo *git.Repository, revision string, groups [][]string) (bool, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return false, err
+	}
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return false, err
+	}
+	if len(groups) == 0 {
+		return false, errors.New("At least one group of changes is needed to split a commit.")
+	}
+
+	target, err := GetCommit(revision, repo)
+	if err != nil {
+		return false, err
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	later, err := commitsAfter(target, repo)
+	if err != nil {
+		return false, err
+	}
+	if len(later) > 0 {
+		err = assertNoChanges(repo)
+		if err != nil {
+			return false, err
+		}
+	}
+	files, err := SplitCandidates(revision, repo)
+	if err != nil {
+		return false, err
+	}
+
+	// The hunks of each file chosen so far, by file path. Groups build on the ones before them.
+	chosen := map[string]map[int]bool{}
+	var trees []*git.Tree
+	for i, group := range groups {
+		added := false
+		for _, entry := range group {
+			matched, err := chooseHunks(entry, files, chosen)
+			if err != nil {
+				return false, err
+			}
+			added = added || matched
+		}
+		if !added {
+			return false, errors.New("Group " + strconv.Itoa(i+1) + " has no changes that aren't in an earlier group.")
+		}
+
+		tree, err := partialTree(target, files, chosen, repo)
+		if err != nil {
+			return false, err
+		}
+		trees = append(trees, tree)
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return false, err
+	}
+	if !trees[len(trees)-1].Id().Equal(targetTree.Id()) {
+		trees = append(trees, targetTree)
+	}
+	if len(trees) < 2 {
+		return false, errors.New("The groups take all of the commit's changes, so it wouldn't be split.\nLeave some changes out, or give another group.")
+	}
+
+	committer, err := userSignature(repo)
+	if err != nil {
+		return false, err
+	}
+	// Each part keeps the original message, with its part number added to the first line.
+	// The longer first lines must still follow the message rules.
+	policy, err := LoadMessagePolicy(repo)
+	if err != nil {
+		return false, err
+	}
+	original := strings.TrimRight(target.Message(), "\n")
+	subjectEnd := strings.Index(original, "\n")
+	if subjectEnd < 0 {
+		subjectEnd = len(original)
+	}
+	var messages []string
+	for i := range trees {
+		message := original[:subjectEnd] + " (part " + strconv.Itoa(i+1) + " of " + strconv.Itoa(len(trees)) + ")" + original[subjectEnd:]
+		err = policy.Check(message)
+		if err != nil {
+			return false, errors.New("The messages of the split commits would break the message rules:\n" + err.Error() +
+				"\nReword the commit with metro patch --commit first.")
+		}
+		messages = append(messages, message)
+	}
+
+	parent := target.Parent(0)
+	for i, tree := range trees {
+		oid, err := repo.CreateCommit("", target.Author(), committer, messages[i], tree, parent)
+		if err != nil {
+			return false, err
+		}
+		parent, err = repo.LookupCommit(oid)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// When splitting the head the working directory is left as it is.
+	if len(later) == 0 {
+		return false, repo.ResetToCommit(parent, git.ResetSoft, &git.CheckoutOpts{})
+	}
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	err = repo.ResetToCommit(parent, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return false, err
+	}
+	return replay("split", head.Id(), nil, pickSteps(later), repo)
+}
+
+// Add the hunks selected by a group entry to the chosen hunks.
+// Returns true if any hunks were chosen that weren't already.
+func chooseHunks(entry string, files []SplitFile, chosen map[string]map[int]bool) (bool, error) {
+	// An entry of the form file:n selects a single hunk, if the file has changes.
+	path, hunkNumber := entry, 0
+	if index := strings.LastIndex(entry, ":"); index >= 0 {
+		if n, err := strconv.Atoi(entry[index+1:]); err == nil {
+			path, hunkNumber = entry[:index], n
+		}
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	found := false
+	added := false
+	for _, file := range files {
+		if file.Path != path && !(hunkNumber == 0 && strings.HasPrefix(file.Path, path+"/")) {
+			continue
+		}
+		found = true
+		if chosen[file.Path] == nil {
+			chosen[file.Path] = map[int]bool{}
+		}
+
+		if hunkNumber == 0 {
+			// Files without hunks, such as binary files, can only be chosen as a whole.
+			count := len(file.hunks)
+			if count == 0 {
+				count = 1
+			}
+			for i := 0; i < count; i++ {
+				if !chosen[file.Path][i] {
+					chosen[file.Path][i] = true
+					added = true
+				}
+			}
+		} else {
+			if hunkNumber > len(file.hunks) {
+				return false, errors.New(file.Path + " only has " + strconv.Itoa(len(file.hunks)) + " hunks.")
+			}
+			if !chosen[file.Path][hunkNumber-1] {
+				chosen[file.Path][hunkNumber-1] = true
+				added = true
+			}
+		}
+	}
+	if !found {
+		return false, errors.New("The commit doesn't change " + path + ".")
+	}
+	return added, nil
+}
+
+// Build the tree of the target commit's parent with only the chosen hunks of the target commit applied.
+func partialTree(target *git.Commit, files []SplitFile, chosen map[string]map[int]bool, repo *git.Repository) (*git.Tree, error) {
+	parentTree, err := target.Parent(0).Tree()
+	if err != nil {
+		return nil, err
+	}
+	index, err := git.NewIndex()
+	if err != nil {
+		return nil, err
+	}
+	err = index.ReadTree(parentTree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		hunks := chosen[file.Path]
+		if len(hunks) == 0 {
+			continue
+		}
+
+		// With every hunk chosen, the file is exactly as in the target commit.
+		if len(hunks) >= len(file.hunks) {
+			if file.delta.Status == git.DeltaDeleted {
+				err = index.RemoveByPath(file.Path)
+			} else {
+				err = index.Add(&git.IndexEntry{
+					Mode: git.Filemode(file.delta.NewFile.Mode),
+					Id:   file.delta.NewFile.Oid,
+					Path: file.Path,
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var old []byte
+		if file.delta.Status != git.DeltaAdded {
+			blob, err := repo.LookupBlob(file.delta.OldFile.Oid)
+			if err != nil {
+				return nil, err
+			}
+			old = blob.Contents()
+		}
+		var selected []splitHunk
+		for i, hunk := range file.hunks {
+			if hunks[i] {
+				selected = append(selected, hunk)
+			}
+		}
+		oid, err := repo.CreateBlobFromBuffer([]byte(applyHunks(string(old), selected)))
+		if err != nil {
+			return nil, err
+		}
+		mode := file.delta.NewFile.Mode
+		if file.delta.Status == git.DeltaDeleted {
+			mode = file.delta.OldFile.Mode
+		}
+		err = index.Add(&git.IndexEntry{Mode: git.Filemode(mode), Id: oid, Path: file.Path})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oid, err := index.WriteTreeTo(repo)
+	if err != nil {
+		return nil, err
+	}
+	return repo.LookupTree(oid)
+}
+
+// Apply the given hunks, in order, to the old contents of a file.
+func applyHunks(old string, hunks []splitHunk) string {
+	oldLines := strings.SplitAfter(old, "\n")
+	if len(oldLines) > 0 && oldLines[len(oldLines)-1] == "" {
+		oldLines = oldLines[:len(oldLines)-1]
+	}
+
+	var result []string
+	position := 0
+	for _, hunk := range hunks {
+		// A hunk that only adds lines starts after its old start line rather than at it.
+		start := hunk.OldStart - 1
+		if hunk.OldLines == 0 {
+			start = hunk.OldStart
+		}
+		result = append(result, oldLines[position:start]...)
+		for _, line := range hunk.lines {
+			switch line.Origin {
+			case git.DiffLineContext, git.DiffLineAddition:
+				result = append(result, line.Content)
+			case git.DiffLineContextEOFNL, git.DiffLineAddEOFNL, git.DiffLineDelEOFNL:
+				// "\ No newline at end of file" isn't part of the file,
+				// the line before it is already without its newline.
+			}
+		}
+		position = start + hunk.OldLines
+	}
+	result = append(result, oldLines[position:]...)
+	return strings.Join(result, "")
+}
+
+// Returns the files changed between two commits with their hunks, sorted by path.
+func commitHunks(from *git.Commit, to *git.Commit, repo *git.Repository) ([]SplitFile, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	diff, err := repo.DiffTreeToTree(fromTree, toTree, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer diff.Free()
+
+	var files []SplitFile
+	err = diff.ForEach(func(delta git.DiffDelta, _ float64) (git.DiffForEachHunkCallback, error) {
+		path := delta.NewFile.Path
+		if delta.Status == git.DeltaDeleted {
+			path = delta.OldFile.Path
+		}
+		files = append(files, SplitFile{Path: path, delta: delta})
+		file := &files[len(files)-1]
+
+		return func(hunk git.DiffHunk) (git.DiffForEachLineCallback, error) {
+			file.Hunks = append(file.Hunks, strings.TrimSpace(hunk.Header))
+			file.hunks = append(file.hunks, splitHunk{DiffHunk: hunk})
+			h := &file.hunks[len(file.hunks)-1]
+
+			return func(line git.DiffLine) error {
+				h.lines = append(h.lines, line)
+				return nil
+			}, nil
+		}, nil
+	}, git.DiffDetailLines)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
@@ -0,0 +1,180 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestApplyHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n"
+	change := splitHunk{
+		DiffHunk: git.DiffHunk{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3},
+		lines: []git.DiffLine{
+			{Origin: git.DiffLineContext, Content: "1\n"},
+			{Origin: git.DiffLineDeletion, Content: "2\n"},
+			{Origin: git.DiffLineAddition, Content: "two\n"},
+			{Origin: git.DiffLineContext, Content: "3\n"},
+		},
+	}
+	// A hunk that only adds lines, after line 5.
+	add := splitHunk{
+		DiffHunk: git.DiffHunk{OldStart: 5, OldLines: 0, NewStart: 6, NewLines: 1},
+		lines:    []git.DiffLine{{Origin: git.DiffLineAddition, Content: "6\n"}},
+	}
+
+	tests := []struct {
+		hunks []splitHunk
+		want  string
+	}{
+		{nil, old},
+		{[]splitHunk{change}, "1\ntwo\n3\n4\n5\n"},
+		{[]splitHunk{add}, "1\n2\n3\n4\n5\n6\n"},
+		{[]splitHunk{change, add}, "1\ntwo\n3\n4\n5\n6\n"},
+	}
+	for i, test := range tests {
+		if got := applyHunks(old, test.hunks); got != test.want {
+			t.Errorf("test %d: applyHunks = %q, want %q", i, got, test.want)
+		}
+	}
+	if got := applyHunks("", []splitHunk{{DiffHunk: git.DiffHunk{}, lines: add.lines}}); got != "6\n" {
+		t.Errorf("applyHunks to a new file = %q, want %q", got, "6\n")
+	}
+
+	// Files without a newline at the end have a marker after their last line in the diff.
+	noNewline := "\n\\ No newline at end of file\n"
+	addNewline := splitHunk{
+		DiffHunk: git.DiffHunk{OldStart: 2, OldLines: 2, NewStart: 2, NewLines: 2},
+		lines: []git.DiffLine{
+			{Origin: git.DiffLineContext, Content: "2\n"},
+			{Origin: git.DiffLineDeletion, Content: "3"},
+			{Origin: git.DiffLineDelEOFNL, Content: noNewline},
+			{Origin: git.DiffLineAddition, Content: "three\n"},
+		},
+	}
+	if got := applyHunks("1\n2\n3", []splitHunk{addNewline}); got != "1\n2\nthree\n" {
+		t.Errorf("applyHunks adding the last newline = %q, want %q", got, "1\n2\nthree\n")
+	}
+	addLast := splitHunk{
+		DiffHunk: git.DiffHunk{OldStart: 3, OldLines: 1, NewStart: 3, NewLines: 2},
+		lines: []git.DiffLine{
+			{Origin: git.DiffLineContext, Content: "3\n"},
+			{Origin: git.DiffLineAddition, Content: "4"},
+			{Origin: git.DiffLineAddEOFNL, Content: noNewline},
+		},
+	}
+	if got := applyHunks("1\n2\n3\n", []splitHunk{addLast}); got != "1\n2\n3\n4" {
+		t.Errorf("applyHunks adding a last line without a newline = %q, want %q", got, "1\n2\n3\n4")
+	}
+	keepLast := splitHunk{
+		DiffHunk: git.DiffHunk{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3},
+		lines: []git.DiffLine{
+			{Origin: git.DiffLineDeletion, Content: "1\n"},
+			{Origin: git.DiffLineAddition, Content: "one\n"},
+			{Origin: git.DiffLineContext, Content: "2\n"},
+			{Origin: git.DiffLineContext, Content: "3"},
+			{Origin: git.DiffLineContextEOFNL, Content: noNewline},
+		},
+	}
+	if got := applyHunks("1\n2\n3", []splitHunk{keepLast}); got != "one\n2\n3" {
+		t.Errorf("applyHunks keeping a last line without a newline = %q, want %q", got, "one\n2\n3")
+	}
+}
+
+func TestSplitByHunksAndFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": numberedLines(20, nil)})
+	commitTestFiles(t, repo, "Change a and add b", map[string]string{
+		"a.txt": numberedLines(20, map[int]string{2: "two", 18: "eighteen"}),
+		"b.txt": "b\n",
+	})
+
+	files, err := SplitCandidates("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "a.txt" || len(files[0].Hunks) != 2 || files[1].Path != "b.txt" {
+		t.Fatalf("SplitCandidates = %+v, want a.txt with 2 hunks and b.txt", files)
+	}
+
+	conflicts, err := Split(repo, "HEAD", [][]string{{"a.txt:1"}, {"b.txt"}})
+	if err != nil || conflicts {
+		t.Fatalf("Split = %v, %v", conflicts, err)
+	}
+
+	// Newest first: the remaining hunk, then b.txt, then the first hunk of a.txt.
+	parts, err := LineHistory("HEAD", 4, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts[3].Summary() != "Add a" {
+		t.Fatalf("Split left %q below the parts, want the original parent", parts[3].Summary())
+	}
+	wantA := []string{
+		numberedLines(20, map[int]string{2: "two", 18: "eighteen"}),
+		numberedLines(20, map[int]string{2: "two"}),
+		numberedLines(20, map[int]string{2: "two"}),
+	}
+	wantB := []string{"b\n", "b\n", ""}
+	for i := 0; i < 3; i++ {
+		part := parts[i]
+		if !strings.Contains(part.Summary(), "(part "+strconv.Itoa(3-i)+" of 3)") {
+			t.Errorf("part %d has summary %q", 3-i, part.Summary())
+		}
+		if got := testFileAt(t, part, "a.txt", repo); got != wantA[i] {
+			t.Errorf("part %d a.txt = %q, want %q", 3-i, got, wantA[i])
+		}
+		if got := testFileAt(t, part, "b.txt", repo); got != wantB[i] {
+			t.Errorf("part %d b.txt = %q, want %q", 3-i, got, wantB[i])
+		}
+	}
+}
+
+func TestSplitNeedsTwoParts(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	head := commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+
+	_, err := Split(repo, "HEAD", [][]string{{"b.txt"}})
+	if err == nil {
+		t.Fatal("Split with one group taking every change succeeded, want error")
+	}
+	current, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Id().Equal(head.Id()) {
+		t.Error("A refused split changed the head")
+	}
+}
+
+func TestSplitFollowsMessageRules(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	subject := "Add b and c"
+	head := commitTestFiles(t, repo, subject, map[string]string{"b.txt": "b\n", "c.txt": "c\n"})
+	err := setConfigString(maxSubjectLengthKey, strconv.Itoa(len(subject)+5), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// " (part 1 of 2)" would take the first line over the limit.
+	_, err = Split(repo, "HEAD", [][]string{{"b.txt"}})
+	if err == nil || !strings.Contains(err.Error(), "message rules") {
+		t.Fatalf("Split making too long a first line = %v, want a message rules error", err)
+	}
+	current, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Id().Equal(head.Id()) {
+		t.Error("A refused split changed the head")
+	}
+}
@@ -0,0 +1,78 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// Replaces the given number of commits at the head of the current line with a single commit
+// containing all of their changes. The working directory is left unchanged.
+// If message is empty the messages of the squashed commits are combined.
+// Absorb commits can't be squashed, as the squashed commit would lose the absorbed line.
+func Squash(repo *git.Repository, commitsBack int, message string) error {
+	err := AssertMerging(repo)
+	if err != nil {
+		return err
+	}
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return err
+	}
+	if commitsBack < 2 {
+		return errors.New("At least two commits are needed to squash.")
+	}
+
+	commits, err := CommitsToDelete(repo, commitsBack)
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		if commit.ParentCount() > 1 {
+			return errors.New("Commit " + ShortID(commit) + " is an absorb, squashing it would lose the absorbed changes.")
+		}
+	}
+	if message == "" {
+		message = SquashMessage(commits)
+	}
+	// The squashed commit has the same contents as the current head.
+	tree, err := commits[0].Tree()
+	if err != nil {
+		return err
+	}
+
+	// Move the head back without touching the index or working directory,
+	// then commit the old head's tree on top.
+	err = DeleteCommits(repo, commitsBack, false)
+	if err != nil {
+		return err
+	}
+	parent, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return err
+	}
+	return commitTree(repo, message, tree, parent)
+}
+
+// Combine the messages of the given commits, newest first, into one message for their squashed commit.
+func SquashMessage(commits []*git.Commit) string {
+	var messages []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		messages = append(messages, commits[i].Message())
+	}
+	return combineMessages(messages)
+}
+
+// Combine commit messages, listing them in the given order, with their trailers merged at the end.
+func combineMessages(messages []string) string {
+	var bodies []string
+	var trailers []Trailer
+	for _, message := range messages {
+		body, messageTrailers := ParseTrailers(message)
+		if strings.TrimSpace(body) != "" {
+			bodies = append(bodies, strings.TrimSpace(body))
+		}
+		trailers = append(trailers, messageTrailers...)
+	}
+	return AddTrailers(strings.Join(bodies, "\n\n"), trailers)
+}
@@ -0,0 +1,59 @@
+package metro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSquash(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	base := commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+	commitTestFiles(t, repo, "Add b", map[string]string{"b.txt": "b\n"})
+	head := commitTestFiles(t, repo, "Add c", map[string]string{"c.txt": "c\n"})
+
+	if err := Squash(repo, 1, ""); err == nil {
+		t.Error("Squash of a single commit succeeded, want error")
+	}
+	err := Squash(repo, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	squashed, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if squashed.ParentCount() != 1 || !squashed.ParentId(0).Equal(base.Id()) {
+		t.Error("Squash didn't replace the commits with one on top of the commit before them")
+	}
+	if !squashed.TreeId().Equal(head.TreeId()) {
+		t.Error("The squashed commit doesn't have the changes of the squashed commits")
+	}
+	// The combined message lists the oldest commit first.
+	message := squashed.Message()
+	if !strings.HasPrefix(message, "Add b") || !strings.Contains(message, "Add c") {
+		t.Errorf("Squashed commit has message %q, want both messages", message)
+	}
+	if err := assertNoChanges(repo); err != nil {
+		t.Errorf("Squash changed the working directory: %v", err)
+	}
+}
+
+func TestSquashRefusesAbsorbs(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	absorb := absorbTestLine(t, repo, "feature")
+	commitTestFiles(t, repo, "Add a", map[string]string{"a.txt": "a\n"})
+
+	err := Squash(repo, 2, "Squashed")
+	if err == nil || !strings.Contains(err.Error(), "absorb") {
+		t.Errorf("Squash of an absorb = %v, want error", err)
+	}
+	parent, err := GetCommit("HEAD^", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parent.Id().Equal(absorb.Id()) {
+		t.Error("A refused Squash changed the line")
+	}
+}
@@ -0,0 +1,117 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"sort"
+	"strings"
+)
+
+// The most lines suggested when a name isn't found.
+const maxSuggestions = 3
+
+// Returns the line a name refers to. The name is tried as the full name of a line, then as any other exact
+// revision such as a commit ID or mark, and only then as the start of exactly one line's name.
+// Returns "" if the name is another revision, or if nothing matches it.
+func ResolveLine(name string, repo *git.Repository) (string, error) {
+	if name == "" {
+		return "", errors.New("Line name required.")
+	}
+	if LineExists(name, repo) {
+		return name, nil
+	}
+	// Marks and commit IDs can't be shadowed by lines that happen to start with them.
+	if CommitExists(name, repo) {
+		return "", nil
+	}
+	lines, err := Lines(repo)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, name) {
+			matches = append(matches, line)
+		}
+	}
+	if len(matches) > 1 {
+		return "", errors.New("More than one line starts with " + name + ": " + strings.Join(matches, ", "))
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return "", nil
+}
+
+// Returns the lines with names closest to the given name, closest first.
+func SuggestLines(name string, repo *git.Repository) ([]string, error) {
+	lines, err := Lines(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow roughly one mistake for every three characters typed.
+	maxDistance := len(name)/3 + 1
+	distances := map[string]int{}
+	var suggestions []string
+	for _, line := range lines {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(line))
+		// A line containing the name is a likely match however long it is.
+		if strings.Contains(strings.ToLower(line), strings.ToLower(name)) && distance > maxDistance {
+			distance = maxDistance
+		}
+		if distance <= maxDistance {
+			distances[line] = distance
+			suggestions = append(suggestions, line)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return distances[suggestions[i]] < distances[suggestions[j]]
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions, nil
+}
+
+// An error saying there is no line with the given name, suggesting the closest lines.
+func noLineError(message string, name string, repo *git.Repository) error {
+	suggestions, err := SuggestLines(name, repo)
+	if err == nil && len(suggestions) > 0 {
+		message += "\nDid you mean " + strings.Join(suggestions, " or ") + "?"
+	}
+	return errors.New(message)
+}
+
+// The number of single character insertions, deletions and substitutions needed to turn a into b.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
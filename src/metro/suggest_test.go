@@ -0,0 +1,97 @@
+package metro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"feature", "feature", 0},
+		{"featur", "feature", 1},
+		{"feature", "faeture", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestResolveLine(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortID := ShortID(head)
+
+	for _, line := range []string{"v1.0-maint", "feature-login", "feature-logout", shortID + "-a", shortID + "-b"} {
+		_, err = CreateBranch(line, repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = CreateMark("v1.0", "HEAD", "", false, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+		// Part of the expected error, or "" for none.
+		err string
+	}{
+		{"v1.0-maint", "v1.0-maint", ""},
+		{"v1.0-m", "v1.0-maint", ""},
+		// Marks and commit IDs are matched before line prefixes.
+		{"v1.0", "", ""},
+		{shortID, "", ""},
+		{"feature-log", "", "More than one line"},
+		{"nothing", "", ""},
+		{"", "", "required"},
+	}
+	for _, test := range tests {
+		got, err := ResolveLine(test.name, repo)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ResolveLine(%q) = %q, %v, want error containing %q", test.name, got, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ResolveLine(%q) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestGetCommitLinePrefix(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	_, err := CreateBranch("feature-login", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetCommit("feature-lo", repo); err != nil {
+		t.Errorf("GetCommit with a line prefix = %v", err)
+	}
+	_, err = GetCommit("featur-login", repo)
+	if err == nil || !strings.Contains(err.Error(), "Did you mean feature-login?") {
+		t.Errorf("GetCommit with a misspelt line = %v, want a suggestion", err)
+	}
+	// Malformed revisions keep their own error rather than being treated as missing lines.
+	_, err = GetCommit("HEAD^{nonsense}", repo)
+	if err == nil || strings.Contains(err.Error(), "No line") {
+		t.Errorf("GetCommit with a malformed revision = %v, want the revision error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+// The remote that sync shares with if no other is given.
+const DefaultRemote = "origin"
+
+// Where the metadata fetched from a remote is kept while it is merged with the local metadata.
+func remoteMetadataRef(remote string) string {
+	return "refs/metro/remotes/" + remote + "/metadata"
+}
+
+// Where the marks fetched from a remote are kept while they are added to the local marks.
+func remoteMarksPrefix(remote string) string {
+	return "refs/metro/remotes/" + remote + "/marks/"
+}
+
+// Share the line metadata and marks with a remote.
+// The remote's metadata is fetched and merged with the local metadata, then the result is pushed back.
+// When both sides have metadata for a line, the local metadata is kept.
+// Marks missing on either side are copied over, marks both sides have are left as they are.
+func SyncShared(remoteName string, repo *git.Repository) error {
+	remote, err := repo.Remotes.Lookup(remoteName)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return errors.New("No remote called " + remoteName + ".")
+	}
+	if err != nil {
+		return err
+	}
+	defer remote.Free()
+
+	// Clear out the marks fetched last time, so marks since deleted from the remote aren't added back.
+	fetchedMarks, err := refsWithPrefix(remoteMarksPrefix(remoteName), repo)
+	if err != nil {
+		return err
+	}
+	for name := range fetchedMarks {
+		err = deleteRef(remoteMarksPrefix(remoteName)+name, repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = remote.Fetch([]string{
+		"+" + metadataRef + ":" + remoteMetadataRef(remoteName),
+		"+" + markPrefix + "*:" + remoteMarksPrefix(remoteName) + "*",
+	}, nil, "metro: sync")
+	if err != nil {
+		return err
+	}
+	err = mergeLineMetadata(remoteMetadataRef(remoteName), repo)
+	if err != nil {
+		return err
+	}
+	missing, err := addMarks(remoteMarksPrefix(remoteName), repo)
+	if err != nil {
+		return err
+	}
+
+	var refspecs []string
+	if _, err := repo.References.Lookup(metadataRef); err == nil {
+		refspecs = append(refspecs, metadataRef+":"+metadataRef)
+	}
+	for _, name := range missing {
+		refspecs = append(refspecs, markPrefix+name+":"+markPrefix+name)
+	}
+	if len(refspecs) == 0 {
+		return nil
+	}
+	return remote.Push(refspecs, nil)
+}
+
+// Merge the metadata held by another ref into the local metadata.
+func mergeLineMetadata(otherRef string, repo *git.Repository) error {
+	other, err := repo.References.Lookup(otherRef)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	local, err := repo.References.Lookup(metadataRef)
+	if git.IsErrorCode(err, git.ErrNotFound) {
+		_, err = repo.References.Create(metadataRef, other.Target(), false, "metro: sync metadata")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if local.Target().Equal(other.Target()) {
+		return nil
+	}
+	behind, err := repo.DescendantOf(other.Target(), local.Target())
+	if err != nil {
+		return err
+	}
+	if behind {
+		_, err = local.SetTarget(other.Target(), "metro: sync metadata")
+		return err
+	}
+	ahead, err := repo.DescendantOf(local.Target(), other.Target())
+	if err != nil || ahead {
+		return err
+	}
+
+	// Both sides changed the metadata, so combine them with the local metadata taking precedence.
+	merged, err := lineMetadataAt(otherRef, repo)
+	if err != nil {
+		return err
+	}
+	mine, err := AllLineMetadata(repo)
+	if err != nil {
+		return err
+	}
+	for name, metadata := range mine {
+		merged[name] = metadata
+	}
+	otherCommit, err := repo.LookupCommit(other.Target())
+	if err != nil {
+		return err
+	}
+	return saveLineMetadata(merged, "metro: sync metadata", repo, otherCommit)
+}
+
+// Add the marks under the given prefix that aren't already marks here.
+// Returns the names of the local marks that aren't under the prefix.
+func addMarks(prefix string, repo *git.Repository) ([]string, error) {
+	other, err := refsWithPrefix(prefix, repo)
+	if err != nil {
+		return nil, err
+	}
+	local, err := refsWithPrefix(markPrefix, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, id := range other {
+		if _, ok := local[name]; ok {
+			continue
+		}
+		_, err = repo.References.Create(markPrefix+name, id, false, "metro: sync mark "+name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var missing []string
+	for name := range local {
+		if _, ok := other[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// Returns the targets of the refs whose names start with prefix, by the rest of their names.
+func refsWithPrefix(prefix string, repo *git.Repository) (map[string]*git.Oid, error) {
+	iterator, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+
+	refs := map[string]*git.Oid{}
+	for {
+		ref, err := iterator.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(ref.Name(), prefix) {
+			refs[strings.TrimPrefix(ref.Name(), prefix)] = ref.Target()
+		}
+	}
+	return refs, nil
+}
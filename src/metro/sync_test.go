@@ -0,0 +1,116 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"testing"
+)
+
+func TestSyncSharedMetadata(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	other := newTestRepo(t)
+	defer removeTestRepo(other)
+	remote, err := repo.Remotes.Create(DefaultRemote, other.Workdir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.Free()
+
+	// Both sides describe a line of their own, and both describe feature.
+	set := func(name string, description string, r *git.Repository) {
+		err := SetLineMetadata(name, LineMetadata{Description: description}, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	set("local", "Local line", repo)
+	set("feature", "Described here", repo)
+	set("remote", "Remote line", other)
+	set("feature", "Described there", other)
+
+	err = SyncShared(DefaultRemote, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"local": "Local line", "remote": "Remote line", "feature": "Described here"}
+	for _, r := range []*git.Repository{repo, other} {
+		metadata, err := AllLineMetadata(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(metadata) != len(want) {
+			t.Errorf("After syncing %s has metadata %+v, want %v", r.Workdir(), metadata, want)
+		}
+		for name, description := range want {
+			if metadata[name].Description != description {
+				t.Errorf("After syncing %s describes %s as %q, want %q", r.Workdir(), name, metadata[name].Description, description)
+			}
+		}
+	}
+
+	// Syncing again with nothing changed is a no-op.
+	err = SyncShared(DefaultRemote, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SyncShared("nowhere", repo)
+	if err == nil {
+		t.Error("SyncShared with a missing remote succeeded, want error")
+	}
+}
+
+func TestSyncSharedMarks(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	other := newTestRepo(t)
+	defer removeTestRepo(other)
+	remote, err := repo.Remotes.Create(DefaultRemote, other.Workdir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote.Free()
+
+	mark := func(name string, r *git.Repository) *git.Commit {
+		commit, err := CreateMark(name, "HEAD", "", false, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return commit
+	}
+	localMark := mark("v1", repo)
+	mark("v2", other)
+	// Both sides have a v3, on different commits.
+	mark("v3", repo)
+	theirs := mark("v3", other)
+
+	err = SyncShared(DefaultRemote, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*git.Repository{repo, other} {
+		marks, err := Marks(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range marks {
+			names = append(names, m.Name)
+		}
+		if len(names) != 3 || names[0] != "v1" || names[1] != "v2" || names[2] != "v3" {
+			t.Errorf("After syncing %s has marks %v, want v1, v2 and v3", r.Workdir(), names)
+		}
+	}
+	// Marks both sides have are never overwritten.
+	marks, err := Marks(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !marks[2].Commit.Equal(theirs.Id()) {
+		t.Error("Syncing overwrote the remote's v3")
+	}
+	if !marks[0].Commit.Equal(localMark.Id()) {
+		t.Error("Syncing pushed v1 to the wrong commit")
+	}
+}
@@ -0,0 +1,123 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"regexp"
+	"strings"
+)
+
+// Trailer keys that Metro adds to commit messages.
+const (
+	CoAuthorKey   = "Co-authored-by"
+	SignoffKey    = "Signed-off-by"
+	CopiedFromKey = "Copied-from"
+)
+
+var (
+	trailerRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*): *(.+)$`)
+	personRegex  = regexp.MustCompile(`^[^<>]+ <[^<>@\s]+@[^<>\s]+>$`)
+)
+
+// A key-value line at the end of a commit message, such as "Co-authored-by: Name <email>".
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// Parse a trailer given as key=value on the command line.
+func ParseTrailer(keyValue string) (Trailer, error) {
+	index := strings.Index(keyValue, "=")
+	if index < 1 {
+		return Trailer{}, errors.New("Trailer must be in the form key=value: " + keyValue)
+	}
+	trailer := Trailer{strings.TrimSpace(keyValue[:index]), strings.TrimSpace(keyValue[index+1:])}
+	if !trailerRegex.MatchString(trailer.String()) {
+		return Trailer{}, errors.New("Invalid trailer: " + keyValue)
+	}
+	return trailer, nil
+}
+
+// Create a trailer crediting a co-author, who must be given as "Name <email>".
+func CoAuthorTrailer(person string) (Trailer, error) {
+	person = strings.TrimSpace(person)
+	if !personRegex.MatchString(person) {
+		return Trailer{}, errors.New("Co-author must be in the form \"Name <email>\": " + person)
+	}
+	return Trailer{CoAuthorKey, person}, nil
+}
+
+// Create a trailer certifying that the current user signed off the commit.
+func SignoffTrailer(repo *git.Repository) (Trailer, error) {
+	signature, err := userSignature(repo)
+	if err != nil {
+		return Trailer{}, err
+	}
+	return Trailer{SignoffKey, signature.Name + " <" + signature.Email + ">"}, nil
+}
+
+// Append trailers to a commit message.
+// They are added to the message's existing trailer block if there is one,
+// otherwise a new block is started after a blank line. Trailers already in the message are not repeated.
+func AddTrailers(message string, trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return message
+	}
+	body, existing := ParseTrailers(message)
+
+	var lines []string
+	for _, trailer := range existing {
+		lines = append(lines, trailer.String())
+	}
+	for _, trailer := range trailers {
+		duplicate := false
+		for _, other := range existing {
+			if strings.EqualFold(trailer.Key, other.Key) && trailer.Value == other.Value {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			lines = append(lines, trailer.String())
+			existing = append(existing, trailer)
+		}
+	}
+
+	return body + "\n\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// Split a commit message into its body and the trailers in its last paragraph.
+// If the last paragraph isn't made entirely of trailers, the message has no trailers.
+func ParseTrailers(message string) (string, []Trailer) {
+	message = strings.TrimRight(message, "\n ")
+	index := strings.LastIndex(message, "\n\n")
+	if index < 0 {
+		return message, nil
+	}
+
+	var trailers []Trailer
+	for _, line := range strings.Split(message[index+2:], "\n") {
+		match := trailerRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			return message, nil
+		}
+		trailers = append(trailers, Trailer{match[1], match[2]})
+	}
+	return strings.TrimRight(message[:index], "\n "), trailers
+}
+
+// Returns the co-authors credited in a commit message.
+func CoAuthors(message string) []string {
+	_, trailers := ParseTrailers(message)
+	var authors []string
+	for _, trailer := range trailers {
+		if strings.EqualFold(trailer.Key, CoAuthorKey) {
+			authors = append(authors, trailer.Value)
+		}
+	}
+	return authors
+}
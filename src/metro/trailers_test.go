@@ -0,0 +1,86 @@
+package metro
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		message  string
+		body     string
+		trailers []Trailer
+	}{
+		{"Add X", "Add X", nil},
+		{"Add X\n\nMore detail.\n", "Add X\n\nMore detail.", nil},
+		{
+			"Add X\n\nCo-authored-by: A <a@example.com>\nSigned-off-by: B <b@example.com>\n",
+			"Add X",
+			[]Trailer{{CoAuthorKey, "A <a@example.com>"}, {SignoffKey, "B <b@example.com>"}},
+		},
+		// A last paragraph that isn't all trailers is part of the body.
+		{"Add X\n\nFixes: ABC-1\nand some prose", "Add X\n\nFixes: ABC-1\nand some prose", nil},
+	}
+	for _, test := range tests {
+		body, trailers := ParseTrailers(test.message)
+		if body != test.body || !reflect.DeepEqual(trailers, test.trailers) {
+			t.Errorf("ParseTrailers(%q) = %q, %v, want %q, %v", test.message, body, trailers, test.body, test.trailers)
+		}
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	coAuthor := Trailer{CoAuthorKey, "A <a@example.com>"}
+	issue := Trailer{"Issue", "ABC-1"}
+
+	tests := []struct {
+		message  string
+		trailers []Trailer
+		want     string
+	}{
+		{"Add X", nil, "Add X"},
+		{"Add X\n", []Trailer{coAuthor}, "Add X\n\nCo-authored-by: A <a@example.com>\n"},
+		// Trailers join the existing block, without repeating ones already there.
+		{"Add X\n\nCo-authored-by: A <a@example.com>\n", []Trailer{coAuthor, issue},
+			"Add X\n\nCo-authored-by: A <a@example.com>\nIssue: ABC-1\n"},
+	}
+	for _, test := range tests {
+		if got := AddTrailers(test.message, test.trailers); got != test.want {
+			t.Errorf("AddTrailers(%q, %v) = %q, want %q", test.message, test.trailers, got, test.want)
+		}
+	}
+}
+
+func TestParseTrailer(t *testing.T) {
+	trailer, err := ParseTrailer("Reviewed-by = C <c@example.com>")
+	if err != nil || trailer != (Trailer{"Reviewed-by", "C <c@example.com>"}) {
+		t.Errorf("ParseTrailer = %v, %v", trailer, err)
+	}
+	for _, keyValue := range []string{"no equals", "=value", "bad key=value"} {
+		if _, err := ParseTrailer(keyValue); err == nil {
+			t.Errorf("ParseTrailer(%q) succeeded, want error", keyValue)
+		}
+	}
+}
+
+func TestCoAuthorTrailer(t *testing.T) {
+	if _, err := CoAuthorTrailer("A <a@example.com>"); err != nil {
+		t.Errorf("CoAuthorTrailer with a valid person = %v", err)
+	}
+	for _, person := range []string{"A", "a@example.com", "A <a>"} {
+		if _, err := CoAuthorTrailer(person); err == nil {
+			t.Errorf("CoAuthorTrailer(%q) succeeded, want error", person)
+		}
+	}
+}
+
+func TestCombineMessages(t *testing.T) {
+	got := combineMessages([]string{
+		"Add Y\n\nCo-authored-by: B <b@example.com>\n",
+		"Add X\n\nCo-authored-by: A <a@example.com>\n",
+	})
+	want := "Add Y\n\nAdd X\n\nCo-authored-by: B <b@example.com>\nCo-authored-by: A <a@example.com>\n"
+	if got != want {
+		t.Errorf("combineMessages = %q, want %q", got, want)
+	}
+}
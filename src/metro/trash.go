@@ -0,0 +1,244 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Deleted lines are kept under this namespace as refs/metro/trash/<unix time>/<line name>.
+	// Lines deleted within the same second get keys of the form <unix time>-<n> instead.
+	trashPrefix = "refs/metro/trash/"
+	// Config key for the number of days deleted lines are kept.
+	trashExpiryKey = "metro.trashExpiryDays"
+	// Number of days deleted lines are kept if trashExpiryKey isn't set.
+	defaultTrashExpiryDays = 30
+)
+
+// A deleted line that can be restored.
+type TrashEntry struct {
+	Name    string
+	Deleted time.Time
+	Id      *git.Oid
+	// The ref holding the line in the trash.
+	ref string
+	// Orders lines deleted within the same second, later deletions have higher numbers.
+	sequence int
+}
+
+// Move a branch and its WIP, if any, into the trash.
+func trashBranch(name string, repo *git.Repository) error {
+	branch, err := repo.LookupBranch(name, git.BranchLocal)
+	if err != nil {
+		return err
+	}
+
+	names := []string{name}
+	if CommitExists(name+WipString, repo) {
+		names = append(names, name+WipString)
+	}
+	key := trashKey(names, repo)
+	for _, n := range names {
+		if n != name {
+			branch, err = repo.LookupBranch(n, git.BranchLocal)
+			if err != nil {
+				return err
+			}
+		}
+		// Never overwrite a line already in the trash, it couldn't be restored again.
+		_, err = repo.References.Create(trashPrefix+key+"/"+n, branch.Target(), false, "metro: delete line "+n)
+		if err != nil {
+			return err
+		}
+		err = branch.Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns a key for the trash refs of the given branches that no line in the trash uses yet.
+func trashKey(names []string, repo *git.Repository) string {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	for sequence := 0; ; sequence++ {
+		key := now
+		if sequence > 0 {
+			key += "-" + strconv.Itoa(sequence)
+		}
+		taken := false
+		for _, n := range names {
+			if _, err := repo.References.Lookup(trashPrefix + key + "/" + n); err == nil {
+				taken = true
+			}
+		}
+		if !taken {
+			return key
+		}
+	}
+}
+
+// Returns the lines in the trash, most recently deleted first.
+// WIP branches are included with their lines and not listed separately.
+func TrashedLines(repo *git.Repository) ([]TrashEntry, error) {
+	entries, err := trashEntries(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []TrashEntry
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name, WipString) {
+			lines = append(lines, entry)
+		}
+	}
+	return lines, nil
+}
+
+// Restore the most recently deleted line with the given name, along with its WIP.
+// If no deleted line has the name, an archived line with it is restored instead.
+func RestoreBranch(name string, repo *git.Repository) error {
+	if LineExists(name, repo) || LineExists(name+WipString, repo) {
+		return errors.New("A line called " + name + " already exists.")
+	}
+	entries, err := trashEntries(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name != name {
+			continue
+		}
+		toRestore := []TrashEntry{entry}
+		for _, wip := range entries {
+			if wip.ref == entry.ref+WipString {
+				toRestore = append(toRestore, wip)
+			}
+		}
+
+		for _, e := range toRestore {
+			commit, err := repo.LookupCommit(e.Id)
+			if err != nil {
+				return err
+			}
+			_, err = repo.CreateBranch(e.Name, commit, false)
+			if err != nil {
+				return err
+			}
+			err = deleteRef(e.ref, repo)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return restoreArchived(name, repo)
+}
+
+// Permanently delete every line in the trash.
+// Returns the number of lines deleted.
+func EmptyTrash(repo *git.Repository) (int, error) {
+	entries, err := trashEntries(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		err = deleteRef(entry.ref, repo)
+		if err != nil {
+			return count, err
+		}
+		if !strings.HasSuffix(entry.Name, WipString) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Permanently delete lines that have been in the trash for longer than the configured number of days.
+func ExpireTrash(repo *git.Repository) error {
+	days, err := configInt(trashExpiryKey, defaultTrashExpiryDays, repo)
+	if err != nil {
+		return err
+	}
+	entries, err := trashEntries(repo)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	for _, entry := range entries {
+		if entry.Deleted.Before(cutoff) {
+			err = deleteRef(entry.ref, repo)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns every branch in the trash, including WIP branches, most recently deleted first.
+func trashEntries(repo *git.Repository) ([]TrashEntry, error) {
+	iterator, err := repo.NewReferenceIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+
+	var entries []TrashEntry
+	for {
+		ref, err := iterator.Next()
+		if git.IsErrorCode(err, git.ErrIterOver) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(ref.Name(), trashPrefix) {
+			continue
+		}
+
+		// Skip anything in the namespace that Metro didn't put there.
+		parts := strings.SplitN(strings.TrimPrefix(ref.Name(), trashPrefix), "/", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		key := strings.SplitN(parts[0], "-", 2)
+		seconds, err := strconv.ParseInt(key[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		sequence := 0
+		if len(key) == 2 {
+			sequence, err = strconv.Atoi(key[1])
+			if err != nil {
+				continue
+			}
+		}
+		entries = append(entries, TrashEntry{parts[1], time.Unix(seconds, 0), ref.Target(), ref.Name(), sequence})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Deleted.Equal(entries[j].Deleted) {
+			return entries[i].sequence > entries[j].sequence
+		}
+		return entries[i].Deleted.After(entries[j].Deleted)
+	})
+	return entries, nil
+}
+
+// Delete a ref by its full name.
+func deleteRef(name string, repo *git.Repository) error {
+	ref, err := repo.References.Lookup(name)
+	if err != nil {
+		return err
+	}
+	return ref.Delete()
+}
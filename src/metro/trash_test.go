@@ -0,0 +1,98 @@
+package metro
+
+import (
+	git "github.com/libgit2/git2go"
+	"strings"
+	"testing"
+)
+
+func TestTrashKeepsLinesDeletedInTheSameSecond(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete two different versions of a line straight after each other.
+	var tips []string
+	for i := 0; i < 2; i++ {
+		branch, err := CreateBranch("feature", repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			err = SwitchBranch("feature", repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			commitTestFiles(t, repo, "Second version", map[string]string{"f.txt": "f\n"})
+			err = SwitchBranch(main, repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			branch, err = repo.LookupBranch("feature", git.BranchLocal)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		tips = append(tips, branch.Target().String())
+		err = DeleteBranch("feature", repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := TrashedLines(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("TrashedLines = %+v, want both deleted lines", entries)
+	}
+	// The most recent deletion is listed, and restored, first.
+	if entries[0].Id.String() != tips[1] || entries[1].Id.String() != tips[0] {
+		t.Errorf("TrashedLines = %+v, want the second version first", entries)
+	}
+	err = RestoreBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := GetCommit("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Id().String() != tips[1] {
+		t.Errorf("RestoreBranch restored %s, want the second version %s", restored.Id(), tips[1])
+	}
+}
+
+func TestRestoreLineNamedLikeMark(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+
+	_, err := CreateBranch("release", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DeleteBranch("release", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateMark("release", "HEAD", "", false, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RestoreBranch("release", repo)
+	if err != nil {
+		t.Fatalf("RestoreBranch with a mark of the same name = %v", err)
+	}
+	if !LineExists("release", repo) {
+		t.Error("RestoreBranch didn't restore the line")
+	}
+	err = RestoreBranch("release", repo)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("RestoreBranch over an existing line = %v, want error", err)
+	}
+}
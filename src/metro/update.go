@@ -0,0 +1,203 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"strings"
+)
+
+func parentLineKey(name string) string {
+	return "branch." + name + ".metroParent"
+}
+
+// Returns the line the given line grew from, or "" if none is recorded.
+func ParentLine(name string, repo *git.Repository) (string, error) {
+	return configString(parentLineKey(name), repo)
+}
+
+// Record the line the given line grew from, which it is updated from by Update.
+// The start of the parent's name is enough. Returns the full name of the parent line.
+func SetParentLine(name string, parent string, repo *git.Repository) (string, error) {
+	line, err := ResolveLine(parent, repo)
+	if err != nil {
+		return "", err
+	}
+	if line == "" || strings.HasSuffix(line, WipString) {
+		return "", noLineError("No line called "+parent+".", parent, repo)
+	}
+	if name == line {
+		return "", errors.New("A line can't grow from itself.")
+	}
+	return line, setConfigString(parentLineKey(name), line, repo)
+}
+
+// Returns the names of the lines that other lines grew from.
+func parentLines(repo *git.Repository) (map[string]bool, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := config.NewIteratorGlob(`^branch\..*\.metroparent$`)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+
+	parents := map[string]bool{}
+	for entry, err := iterator.Next(); err == nil; entry, err = iterator.Next() {
+		parents[entry.Value] = true
+	}
+	return parents, nil
+}
+
+// Point lines that grew from a renamed line at its new name.
+func renameParentLine(oldName string, newName string, repo *git.Repository) error {
+	config, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	iterator, err := config.NewIteratorGlob(`^branch\..*\.metroparent$`)
+	if err != nil {
+		return err
+	}
+	defer iterator.Free()
+
+	var children []string
+	for entry, err := iterator.Next(); err == nil; entry, err = iterator.Next() {
+		if entry.Value == oldName {
+			children = append(children, entry.Name)
+		}
+	}
+	for _, key := range children {
+		err = config.SetString(key, newName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the current line followed by the line it grew from, the line that grew from, and so on.
+func lineChain(repo *git.Repository) ([]string, error) {
+	name, err := CurrentBranchName(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []string{name}
+	for {
+		parent, err := ParentLine(name, repo)
+		if err != nil {
+			return nil, err
+		}
+		if parent == "" || !LineExists(parent, repo) {
+			return chain, nil
+		}
+		for _, line := range chain {
+			if line == parent {
+				return nil, errors.New("The parent lines of " + chain[0] + " loop back to " + parent + ".")
+			}
+		}
+		chain = append(chain, parent)
+		name = parent
+	}
+}
+
+// Brings the current line up to date with the latest commits of the line it grew from.
+// If that line also grew from another, it is brought up to date first, and so on up the chain.
+// Each line either absorbs its parent, or has its own commits replayed on top of its parent if replayCommits is true.
+// Returns the line that stopped with conflicts, or "" if the whole chain was updated.
+// After resolving the conflicts, Update can be run again from the original line to finish.
+func Update(replayCommits bool, repo *git.Repository) (string, error) {
+	err := AssertMerging(repo)
+	if err != nil {
+		return "", err
+	}
+	err = assertNoChanges(repo)
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := lineChain(repo)
+	if err != nil {
+		return "", err
+	}
+	if len(chain) < 2 {
+		return "", errors.New("Line " + chain[0] + " has no parent line to update from.")
+	}
+	// Restoring the WIP of a line would undo its update, so lines with work in progress are left alone.
+	for _, line := range chain[1 : len(chain)-1] {
+		if CommitExists(line+WipString, repo) {
+			return "", errors.New("Line " + line + " has work in progress, switch to it and commit it before updating.")
+		}
+	}
+
+	// Update from the top of the chain down, so each line gets its parent's latest commits.
+	for i := len(chain) - 2; i >= 0; i-- {
+		line := chain[i]
+		if len(chain) > 2 {
+			err = checkoutBranch(line, repo)
+			if err != nil {
+				return "", err
+			}
+		}
+		conflicts, err := updateLine(chain[i+1], replayCommits, repo)
+		if err != nil {
+			return "", err
+		}
+		if conflicts {
+			return line, nil
+		}
+	}
+	return "", nil
+}
+
+// Brings the current line up to date with the given parent line.
+// Returns true if this stopped with conflicts.
+func updateLine(parent string, replayCommits bool, repo *git.Repository) (bool, error) {
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		return false, err
+	}
+	parentHead, err := GetCommit(parent, repo)
+	if err != nil {
+		return false, err
+	}
+	baseID, err := repo.MergeBase(head.Id(), parentHead.Id())
+	if err != nil {
+		return false, err
+	}
+
+	checkoutOps := git.CheckoutOpts{}
+	checkoutOps.Strategy = git.CheckoutForce
+	if baseID.Equal(parentHead.Id()) {
+		// Already up to date.
+		return false, nil
+	}
+	if baseID.Equal(head.Id()) {
+		// No commits of its own, so the line can just move forward.
+		return false, repo.ResetToCommit(parentHead, git.ResetHard, &checkoutOps)
+	}
+	if !replayCommits {
+		return Absorb(parent, repo)
+	}
+
+	err = assertCanRewrite(repo)
+	if err != nil {
+		return false, err
+	}
+	base, err := repo.LookupCommit(baseID)
+	if err != nil {
+		return false, err
+	}
+	commits, err := commitsAfter(base, repo)
+	if err != nil {
+		current, _ := CurrentBranchName(repo)
+		return false, errors.New("Line " + current + " has absorbed " + parent + " before, so its commits can't be replayed.\nUpdate it by absorbing instead.")
+	}
+	err = repo.ResetToCommit(parentHead, git.ResetHard, &checkoutOps)
+	if err != nil {
+		return false, err
+	}
+	return replay("update", head.Id(), nil, pickSteps(commits), repo)
+}
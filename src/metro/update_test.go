@@ -0,0 +1,94 @@
+package metro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetParentLine(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateMark("v1", "HEAD", "", false, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only lines can be parents, not marks or other revisions.
+	for _, parent := range []string{"v1", head.Id().String(), "HEAD~0", "feature" + WipString} {
+		_, err = SetParentLine("feature", parent, repo)
+		if err == nil || !strings.Contains(err.Error(), "No line called") {
+			t.Errorf("SetParentLine(%q) = %v, want a missing line error", parent, err)
+		}
+	}
+	_, err = SetParentLine("feature", "feature", repo)
+	if err == nil {
+		t.Error("SetParentLine to the line itself succeeded, want error")
+	}
+
+	parent, err := SetParentLine("feature", main[:2], repo)
+	if err != nil || parent != main {
+		t.Fatalf("SetParentLine with a prefix = %q, %v, want %q", parent, err, main)
+	}
+	recorded, err := ParentLine("feature", repo)
+	if err != nil || recorded != main {
+		t.Errorf("ParentLine = %q, %v, want %q", recorded, err, main)
+	}
+}
+
+func TestUpdateAbsorbsParent(t *testing.T) {
+	repo := newTestRepo(t)
+	defer removeTestRepo(repo)
+	main, err := CurrentBranchName(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CreateBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := commitTestFiles(t, repo, "Work on "+main, map[string]string{"main.txt": "main\n"})
+	err = SwitchBranch("feature", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, "Work on feature", map[string]string{"feature.txt": "feature\n"})
+
+	stopped, err := Update(false, repo)
+	if err != nil || stopped != "" {
+		t.Fatalf("Update = %q, %v", stopped, err)
+	}
+	head, err := GetCommit("HEAD", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.ParentCount() != 2 || !head.ParentId(1).Equal(upstream.Id()) {
+		t.Errorf("Update made %s, want an absorb of %s", head.Summary(), upstream.Id())
+	}
+
+	// A deleted parent isn't matched to a mark with the same name.
+	_, err = CreateMark("released", "HEAD", "", false, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = setConfigString(parentLineKey("feature"), "released", repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := lineChain(repo)
+	if err != nil || len(chain) != 1 {
+		t.Errorf("lineChain with a deleted parent = %v, %v, want only feature", chain, err)
+	}
+}
@@ -0,0 +1,104 @@
+package metro
+
+import (
+	"errors"
+	git "github.com/libgit2/git2go"
+	"time"
+)
+
+// Date formats accepted when switching to a point in time, tried in order.
+var dateFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Returns true if the head is a commit being viewed rather than a line.
+func ViewingCommit(repo *git.Repository) (bool, error) {
+	return repo.IsHeadDetached()
+}
+
+// Raises an error if HEAD isn't on a line, since commits can't be made there.
+func assertOnLine(repo *git.Repository) error {
+	viewing, err := ViewingCommit(repo)
+	if err != nil {
+		return err
+	}
+	if viewing {
+		return errors.New("You are viewing an old commit, which is read-only.\nCreate a line from it with metro line <name> --switch to make changes.")
+	}
+	head, err := GetHead(repo)
+	if err != nil {
+		return err
+	}
+	return head.notOnLineError()
+}
+
+// Switch to viewing the given commit without being on a line, saving the WIP of the current line first.
+// Commits can't be made until switching back to a line.
+func SwitchToCommit(revision string, repo *git.Repository) error {
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return err
+	}
+	if ReplayOngoing(repo) {
+		return errors.New("Branch has conflicts, please finish resolving them before switching.\nRun metro resolve when you are done.")
+	}
+
+	viewing, err := ViewingCommit(repo)
+	if err != nil {
+		return err
+	}
+	if viewing {
+		err = assertNoChanges(repo)
+		if err != nil {
+			return errors.New("You have changes while viewing a commit.\nKeep them on a new line with metro line <name> --switch.")
+		}
+	} else {
+		err = SaveWIP(repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	checkoutOps := git.CheckoutOpts{
+		Strategy: git.CheckoutForce | git.CheckoutRemoveUntracked,
+	}
+	err = repo.CheckoutTree(tree, &checkoutOps)
+	if err != nil {
+		return err
+	}
+	return repo.SetHeadDetached(commit.Id())
+}
+
+// Returns the last commit on the line leading up to the given revision that was made at or before the given date.
+// Only the first parent of each commit is followed, so commits from absorbed lines are left out.
+func CommitAt(revision string, date string, repo *git.Repository) (*git.Commit, error) {
+	var when time.Time
+	var err error
+	for _, format := range dateFormats {
+		when, err = time.ParseInLocation(format, date, time.Local)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.New("Invalid date: " + date + "\nUse the form YYYY-MM-DD or YYYY-MM-DD HH:MM.")
+	}
+	// A date on its own includes the whole day.
+	if len(date) == len("2006-01-02") {
+		when = when.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	commit, err := GetCommit(revision, repo)
+	if err != nil {
+		return nil, err
+	}
+	for commit != nil {
+		if !commit.Committer().When.After(when) {
+			return commit, nil
+		}
+		commit = commit.Parent(0)
+	}
+	return nil, errors.New("There are no commits from before " + date + ".")
+}